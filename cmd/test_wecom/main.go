@@ -32,9 +32,9 @@ func main() {
 		"@皮皮虾 豆包 AI 的优势是什么？",
 	}
 
-	fmt.Println("=== 微信交互功能测试 ===\n")
+	fmt.Print("=== 微信交互功能测试 ===\n\n")
 	fmt.Println("机器人应该监听 http://localhost:9001/wecom/message")
-	fmt.Println("确保程序已启动...\n")
+	fmt.Print("确保程序已启动...\n\n")
 
 	for i, question := range testMessages {
 		fmt.Printf("\n[测试 %d] 发送问题: %s\n", i+1, question)
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/history"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+)
+
+// showHistory 从记录文件查询定时任务的执行记录，新的在前
+func showHistory(args []string, stdout, stderr io.Writer) int {
+	fs, configFile := newFlagSet("history", stderr)
+	job := fs.String("job", "", "任务名：daily_report、off_work 或 refresh_calendars")
+	group := fs.String("group", "", "群名称")
+	outcome := fs.String("outcome", "", "执行结果：success、partial、skipped 或 failed")
+	date := fs.String("date", "", "只看某一天，格式 YYYY-MM-DD")
+	days := fs.String("days", "", "只看最近几天（含今天）")
+	limit := fs.String("limit", "20", "最多输出的条数，0 表示不限制")
+	asJSON := fs.Bool("json", false, "以 JSON Lines 输出")
+	if code := parseFlags(fs, configFile, args); code >= 0 {
+		return code
+	}
+
+	log.Init()
+	config.Load()
+	cfg := config.Get()
+	filter, err := history.ParseFilter(*job, *group, *outcome, *date, *days, *limit, time.Now().In(holiday.Location()))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if cfg.History.File == "" {
+		fmt.Fprintln(stderr, "未配置 history.file，执行记录只保存在运行中的服务内存中，请通过 /history 查询")
+		return exitError
+	}
+	store, err := history.Open(cfg.History)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	runs := store.Query(filter)
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		for _, r := range runs {
+			if err := enc.Encode(r); err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitError
+			}
+		}
+		return exitOK
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(stdout, "没有执行记录")
+		return exitOK
+	}
+	writeRuns(stdout, runs)
+	return exitOK
+}
+
+// writeRuns 以表格输出执行记录
+func writeRuns(w io.Writer, runs []history.Run) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "计划时间\t开始时间\t任务\t群\t触发\t结果\t原因\t消息数\t错误")
+	for _, r := range runs {
+		outcome := r.Outcome
+		if r.DryRun {
+			outcome += "(dry-run)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			r.Scheduled.In(holiday.Location()).Format("2006-01-02 15:04"),
+			r.Start.In(holiday.Location()).Format("01-02 15:04:05"),
+			r.Job, dash(r.Group), r.Trigger, outcome, dash(r.SkipReason), r.Messages,
+			dash(strings.Join(r.Errors, "; ")))
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
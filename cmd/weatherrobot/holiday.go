@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+)
+
+// showHoliday 输出群在指定时间的工作日、节假日和假期判断结果，或导出群生效的日历
+func showHoliday(args []string, stdout, stderr io.Writer) int {
+	fs, configFile := newFlagSet("holiday show", stderr)
+	nowArg := nowFlag(fs)
+	icsOutput := fs.String("ics", "", "把生效的日历导出为 ICS 文件（今年和明年）")
+	group := fs.String("group", "", "群名称，按群配置的 calendars 判断，默认第一个群")
+	if code := parseFlags(fs, configFile, args); code >= 0 {
+		return code
+	}
+	clock, err := parseClock(*nowArg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	setup(cronn.NewRunner(clock, nil))
+	defer log.Close()
+	now := clock.Now()
+
+	g, err := cronn.FindGroup(config.Get(), *group)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	cal, err := holiday.Lookup(g.Calendars)
+	if err != nil {
+		fmt.Fprintf(stderr, "群 %s 的日历无效: %v\n", g.Name, err)
+		return exitError
+	}
+
+	if *icsOutput != "" {
+		if err := exportICS(cal, *icsOutput, now); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "已导出日历到 %s\n", *icsOutput)
+		return exitOK
+	}
+
+	sep := strings.Repeat("=", 50)
+	fmt.Fprintln(stdout, sep)
+	fmt.Fprintln(stdout, "工作日/假期判断")
+	fmt.Fprintln(stdout, sep)
+
+	fmt.Fprintf(stdout, "当前时间: %s\n", now.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(stdout, "星期: %v\n", now.Weekday())
+	fmt.Fprintf(stdout, "群: %s，日历: %s\n", g.Name, cal.Name)
+	version, years := holiday.DataVersion()
+	fmt.Fprintf(stdout, "节假日数据版本: %s，覆盖年份: %v\n", version, years)
+
+	// 检查是否为调休补班日
+	isAdjusted, adjusted := cal.IsAdjustedWorkday(now)
+	fmt.Fprintf(stdout, "是否为调休补班日: %v\n", isAdjusted)
+	if isAdjusted && adjusted != nil {
+		fmt.Fprintf(stdout, "补班原因: %s调休\n", adjusted.Name)
+	}
+
+	// 检查是否为工作日
+	fmt.Fprintf(stdout, "是否为工作日: %v\n\n", cal.IsWorkday(now))
+
+	// 检查是否为节假日
+	isFestival, festival := cal.IsFestival(now)
+	fmt.Fprintf(stdout, "是否为节假日: %v\n", isFestival)
+	if isFestival && festival != nil {
+		fmt.Fprintf(stdout, "节假日名称: %s\n", festival.Name)
+		fmt.Fprintf(stdout, "节假日问候: %s\n\n", festival.Greeting)
+	}
+
+	// 检查是否在假期期间
+	allHolidays := append(append(append([]holiday.Holiday{}, cal.Holidays...), config.Get().Holidays...), holiday.ImportedHolidays()...)
+	isHoliday := holiday.IsHoliday(now, allHolidays)
+	fmt.Fprintf(stdout, "是否在假期期间: %v\n\n", isHoliday)
+
+	// 检查是否应该发送天气报告
+	shouldSendReport, isFestival2, festival2 := cal.ShouldSendReminder(clock, config.Get().Holidays)
+	fmt.Fprintln(stdout, sep)
+	fmt.Fprintln(stdout, "天气报告判断结果:")
+	fmt.Fprintf(stdout, "是否应该发送天气报告: %v\n", shouldSendReport)
+	fmt.Fprintf(stdout, "是否为节假日: %v\n", isFestival2)
+	if festival2 != nil {
+		fmt.Fprintf(stdout, "节假日信息: %s - %s\n", festival2.Name, festival2.Greeting)
+	}
+	fmt.Fprintln(stdout)
+
+	// 检查是否应该发送下班提醒
+	shouldSendOffWork, _, _ := cal.ShouldSendOffWorkReminder(clock, config.Get().Holidays)
+	fmt.Fprintln(stdout, sep)
+	fmt.Fprintln(stdout, "下班提醒判断结果:")
+	fmt.Fprintf(stdout, "是否应该发送下班提醒: %v\n\n", shouldSendOffWork)
+
+	// 显示配置的假期
+	fmt.Fprintln(stdout, sep)
+	fmt.Fprintln(stdout, "已配置的假期列表:")
+	if len(allHolidays) == 0 {
+		fmt.Fprintln(stdout, "无自定义假期配置")
+	}
+	for _, h := range allHolidays {
+		fmt.Fprintf(stdout, "- %s: %s 至 %s\n", h.Name, h.StartDate, h.EndDate)
+	}
+	fmt.Fprintln(stdout, sep)
+	return exitOK
+}
+
+// exportICS 把日历 cal 今年和明年生效的部分导出为 ICS 文件
+func exportICS(cal *holiday.Calendar, path string, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer f.Close()
+
+	from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, holiday.Location())
+	to := time.Date(now.Year()+1, 12, 31, 0, 0, 0, 0, holiday.Location())
+	if err := cal.WriteICS(f, config.Get().Holidays, from, to); err != nil {
+		return fmt.Errorf("导出日历失败: %w", err)
+	}
+	return nil
+}
@@ -1,51 +1,203 @@
 package main
 
 import (
-	"time"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"wechatrobot/internal/config"
 	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/history"
+	"wechatrobot/internal/holiday"
 	"wechatrobot/internal/log"
-	"wechatrobot/internal/wecom"
+	"wechatrobot/internal/usage"
+	"wechatrobot/internal/weather"
 
-	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
+const usageText = `用法: weatherrobot <命令> [参数]
+
+命令:
+  serve                   启动机器人（定时任务和企业微信消息接收服务），不带命令时默认执行
+  send report|offwork     立即发送天气报告或下班提醒
+  preview [report|offwork]
+                          输出天气报告和下班提醒的内容，不发送
+  config check            校验配置文件，有问题时以非 0 状态退出
+  holiday show            输出工作日、节假日和假期判断结果
+  history                 查询定时任务的执行记录
+  version                 输出版本信息
+
+通用参数:
+  --config FILE           配置文件路径（默认 ./config/config.yaml）
+
+运行 "weatherrobot <命令> -h" 查看命令的参数。
+`
+
+// 退出状态
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析子命令并执行，返回退出状态
+func run(args []string, stdout, stderr io.Writer) int {
+	// 不带命令或直接跟参数时执行 serve，兼容原来的启动方式
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		return serve(args, stderr)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "serve":
+		return serve(args, stderr)
+	case "send":
+		return send(args, stdout, stderr)
+	case "preview":
+		return preview(args, stdout, stderr)
+	case "config":
+		if len(args) == 0 || args[0] != "check" {
+			fmt.Fprintln(stderr, "用法: weatherrobot config check [--config FILE]")
+			return exitUsage
+		}
+		return checkConfig(args[1:], stdout, stderr)
+	case "holiday":
+		if len(args) == 0 || args[0] != "show" {
+			fmt.Fprintln(stderr, "用法: weatherrobot holiday show [--config FILE] [--now TIME] [--group NAME] [--ics FILE]")
+			return exitUsage
+		}
+		return showHoliday(args[1:], stdout, stderr)
+	case "history":
+		return showHistory(args, stdout, stderr)
+	case "version":
+		return printVersion(stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usageText)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "未知命令 %q\n\n%s", cmd, usageText)
+	return exitUsage
+}
+
+// newFlagSet 创建子命令的参数集，所有子命令都支持 --config
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("weatherrobot "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "", "配置文件路径（默认 ./config/config.yaml）")
+	return fs, configFile
+}
+
+// parseFlags 解析参数并设置配置文件路径，返回值不为 -1 时应以该状态退出
+func parseFlags(fs *flag.FlagSet, configFile *string, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	config.SetFile(*configFile)
+	return -1
+}
+
+// nowFlag 添加模拟当前时间的 --now 参数
+func nowFlag(fs *flag.FlagSet) *string {
+	return fs.String("now", "", "模拟当前时间，格式 YYYY-MM-DD 或 \"YYYY-MM-DD HH:MM\"")
+}
+
+// parseClock 解析 --now，为空时使用系统时间
+func parseClock(now string) (holiday.Clock, error) {
+	if now == "" {
+		return holiday.SystemClock{}, nil
+	}
+	return holiday.ParseNow(now)
+}
+
+// signalContext 返回收到 SIGINT/SIGTERM 时取消的 context
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// setup 初始化日志并加载配置、节假日数据、请假登记和日历，配置无效时退出
+func setup(runner *cronn.Runner) {
 	// 初始化日志
 	log.Init()
 
 	// 加载配置
 	config.Load()
+	cfg := config.Get()
+	if err := log.Configure(cfg.Log); err != nil {
+		logrus.Fatal(err)
+	}
+	now := runner.Clock.Now()
 
-	// 启动企业微信消息接收服务（在后台运行）
-	go wecom.StartWecomServer("9001")
+	// 加载节假日数据
+	if err := holiday.LoadCalendar(cfg.HolidayData, now); err != nil {
+		logrus.Fatal("加载节假日数据失败: ", err)
+	}
 
-	// 创建cron实例（中国时区）
-	// c := cron.New(cron.WithLocation(time.FixedZone("CST", 8*3600)))
-	c := cron.New(cron.WithLocation(time.FixedZone("CST", 8*3600)))
+	// 初始化 LLM 用量统计
+	if err := usage.Init(cfg.AIUsage); err != nil {
+		logrus.Fatal("初始化 LLM 用量统计失败: ", err)
+	}
 
-	// 添加定时任务，每分钟执行一次
-	_, err := c.AddFunc("0 8 * * *", cronn.SendDailyReport)
-	// _, err := c.AddFunc("*/1 * * * *", cronn.SendDailyReport) // 1分钟执行一次
-	if err != nil {
-		logrus.Fatal("创建定时任务失败: ", err)
+	// 加载定时任务执行记录
+	if err := history.Init(cfg.History); err != nil {
+		logrus.Fatal("加载任务执行记录失败: ", err)
 	}
-	logrus.Info("天气报告定时任务已添加（每1分钟执行一次）")
 
-	// 添加定时任务，每天下午6点提醒下班
-	_, err = c.AddFunc("0 18 * * *", cronn.SendOffWorkReminder)
-	// _, err = c.AddFunc("*/1 * * * *", cronn.SendOffWorkReminder) // 1分钟执行一次
+	// 加载请假登记
+	if err := holiday.InitLeaves(cfg.Leave); err != nil {
+		logrus.Fatal("加载请假登记失败: ", err)
+	}
 
-	if err != nil {
-		logrus.Fatal("创建定时任务失败: ", err)
+	// 加载自定义日历（地区节假日、公司假期）
+	if err := holiday.LoadCalendars(cfg.Calendars, now.AddDate(-1, 0, 0), now.AddDate(2, 0, 0)); err != nil {
+		logrus.Fatal("加载自定义日历失败: ", err)
 	}
-	logrus.Info("下班提醒定时任务已添加（每1分钟执行一次）")
 
-	// 启动定时任务
-	c.Start()
-	logrus.Info("天气机器人已启动（包含定时任务和微信交互服务）")
+	// 导入 ICS 日历中的假期和请假
+	runner.RefreshHolidayICS()
+}
+
+// checkConfig 校验配置文件并输出所有问题
+func checkConfig(args []string, stdout, stderr io.Writer) int {
+	fs, configFile := newFlagSet("config check", stderr)
+	if code := parseFlags(fs, configFile, args); code >= 0 {
+		return code
+	}
+	if _, err := config.Check(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, "配置检查通过")
+	return exitOK
+}
 
-	// 保持程序运行
-	select {}
+// dryRunFlags 添加 --dry-run 和 --output 参数
+func dryRunFlags(fs *flag.FlagSet) (dryRun *bool, output *string) {
+	dryRun = fs.Bool("dry-run", false, "消息不发送到群，输出到终端或 --output 指定的文件")
+	output = fs.String("output", "-", "dry-run 时消息的输出文件，- 为标准输出")
+	return dryRun, output
+}
+
+// useDryRun 开启 dry-run 时让 runner 把消息写到输出文件，返回的函数用于关闭文件
+func useDryRun(runner *cronn.Runner, dryRun bool, output string) (func() error, error) {
+	if !dryRun {
+		return func() error { return nil }, nil
+	}
+	sender, closeFile, err := weather.OpenDryRunSender(output)
+	if err != nil {
+		return nil, err
+	}
+	runner.Sender, runner.DryRun = sender, true
+	return closeFile, nil
 }
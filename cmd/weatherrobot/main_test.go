@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+wecom_webhook: "http://127.0.0.1:1/webhook" # 连接被拒绝，发送必然失败
+weather_api_key: "test-key"
+locations: ["101010100"]
+off_work_messages: ["下班啦"]
+timezone: "Asia/Shanghai"
+groups:
+  - name: "研发群"
+    mention_users: ["@all"]
+  - name: "北京"
+    mention_users: ["@all"]
+    calendars: ["cn-mainland"]
+`
+
+// useTempDir 切换到临时目录并写入配置文件，setup 创建的数据文件都写在临时目录中
+func useTempDir(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return file
+}
+
+func TestRun(t *testing.T) {
+	valid := useTempDir(t, testConfig)
+	invalid := filepath.Join(filepath.Dir(valid), "invalid.yaml")
+	output := filepath.Join(filepath.Dir(valid), "dry-run.log")
+	if err := os.WriteFile(invalid, []byte("timezone: \"Mars/Olympus\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   string
+		code   int
+		stdout string // 标准输出应包含的内容
+		stderr string // 标准错误应包含的内容
+	}{
+		{"help", exitOK, "用法: weatherrobot", ""},
+		{"--help", exitOK, "用法: weatherrobot", ""},
+		{"version", exitOK, "weatherrobot dev", ""},
+		{"frobnicate", exitUsage, "", "未知命令 \"frobnicate\""},
+		{"config", exitUsage, "", "weatherrobot config check"},
+		{"config check --config " + valid, exitOK, "配置检查通过", ""},
+		{"config check --config " + invalid, exitError, "", ""},
+		{"config check --verbose", exitUsage, "", "flag provided but not defined"},
+		{"holiday list", exitUsage, "", "weatherrobot holiday show"},
+		{"holiday show -h", exitOK, "", "-group"},
+		{"holiday show --now tomorrow", exitUsage, "", ""},
+		{"holiday show --config " + valid + " --now 2026-10-01", exitOK, "群: 研发群，日历: cn-mainland", ""},
+		{"holiday show --config " + valid + " --now 2026-10-01 --group 北京", exitOK, "是否为节假日: true", ""},
+		{"holiday show --config " + valid + " --group 上海", exitUsage, "", "未配置群 \"上海\""},
+		{"send", exitUsage, "", "weatherrobot send report|offwork"},
+		{"send lunch", exitUsage, "", "未知任务 \"lunch\""},
+		{"send report --now", exitUsage, "", "flag needs an argument"},
+		{"send offwork --config " + valid + " --now 2026-10-19 --dry-run --output " + output, exitOK, "", ""},
+		{"send offwork --config " + valid + " --now 2026-10-19", exitError, "", ""}, // 发送失败
+		{"send offwork --config " + valid + " --now 2026-10-17", exitOK, "", ""},    // 周六跳过
+		{"preview lunch", exitUsage, "", ""},
+		{"history --json=maybe", exitUsage, "", "invalid boolean value"},
+		{"history --config " + valid + " --days abc", exitUsage, "", ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(strings.Fields(tt.args), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: exit %d, want %d\nstderr: %s", tt.args, code, tt.code, stderr.String())
+			continue
+		}
+		if !strings.Contains(stdout.String(), tt.stdout) {
+			t.Errorf("%s: stdout = %q, want %q", tt.args, stdout.String(), tt.stdout)
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", tt.args, stderr.String(), tt.stderr)
+		}
+	}
+
+	if data, err := os.ReadFile(output); err != nil || !strings.Contains(string(data), "下班啦") {
+		t.Errorf("dry-run output = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jobs 可以手动触发的任务
+var jobs = map[string]struct {
+	name string
+	key  string // 指标和执行记录中的任务名
+	run  func(r *cronn.Runner, ctx context.Context)
+}{
+	cronn.JobReport:  {"天气报告", metrics.JobDailyReport, (*cronn.Runner).SendDailyReport},
+	cronn.JobOffWork: {"下班提醒", metrics.JobOffWork, (*cronn.Runner).SendOffWorkReminder},
+}
+
+// send 立即执行一次天气报告或下班提醒，--now 可以模拟指定日期
+// 有群执行失败或部分失败时以非 0 状态退出，便于在 cron、CI 中发现问题
+func send(args []string, stdout, stderr io.Writer) int {
+	const usageLine = "用法: weatherrobot send report|offwork [--config FILE] [--now TIME] [--dry-run [--output FILE]]"
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, usageLine)
+		return exitUsage
+	}
+	job, ok := jobs[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "未知任务 %q\n%s\n", args[0], usageLine)
+		return exitUsage
+	}
+
+	fs, configFile := newFlagSet("send "+args[0], stderr)
+	now := nowFlag(fs)
+	dryRun, output := dryRunFlags(fs)
+	if code := parseFlags(fs, configFile, args[1:]); code >= 0 {
+		return code
+	}
+	clock, err := parseClock(*now)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	runner := cronn.NewRunner(clock, nil)
+	closeOutput, err := useDryRun(runner, *dryRun, *output)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	defer closeOutput()
+	setup(runner)
+	defer log.Close()
+
+	// Ctrl-C 时中止天气查询、AI 生成和发送
+	ctx, stop := signalContext()
+	defer stop()
+	logrus.Infof("手动触发%s（%s）", job.name, clock.Now().Format("2006-01-02 15:04"))
+	job.run(runner, ctx)
+	result, _ := cronn.LastResult(job.key)
+	if result.Outcome == metrics.OutcomeFailed || result.Outcome == metrics.OutcomePartial {
+		logrus.Errorf("%s执行结果为 %s（各群: %v）", job.name, result.Outcome, result.Groups)
+		return exitError
+	}
+	logrus.Infof("%s执行完毕", job.name)
+	return exitOK
+}
+
+// preview 输出某个群的天气报告和下班提醒而不发送，不指定任务时两个都输出
+// --now 只给日期时按配置的执行时间渲染
+func preview(args []string, stdout, stderr io.Writer) int {
+	const usageLine = "用法: weatherrobot preview [report|offwork] [--config FILE] [--now TIME] [--group NAME]"
+	names := []string{cronn.JobReport, cronn.JobOffWork}
+	if len(args) > 0 {
+		if _, ok := jobs[args[0]]; ok {
+			names, args = args[:1], args[1:]
+		}
+	}
+
+	fs, configFile := newFlagSet("preview", stderr)
+	now := nowFlag(fs)
+	group := fs.String("group", "", "群名称，默认第一个群")
+	if code := parseFlags(fs, configFile, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "未知任务 %q\n%s\n", fs.Arg(0), usageLine)
+		return exitUsage
+	}
+	clock, err := parseClock(*now)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	setup(cronn.NewRunner(clock, nil))
+	defer log.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	for _, name := range names {
+		at := clock.Now()
+		if !strings.Contains(*now, " ") {
+			at = cronn.ScheduledTime(config.Get(), name, at)
+		}
+		p, err := cronn.PreviewJob(ctx, name, *group, at)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		if err := p.WriteText(stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	}
+	return exitOK
+}
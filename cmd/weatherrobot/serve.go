@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/health"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/weather"
+	"wechatrobot/internal/wecom"
+
+	"github.com/sirupsen/logrus"
+)
+
+// serve 启动定时任务和企业微信消息接收服务
+func serve(args []string, stderr io.Writer) int {
+	fs, configFile := newFlagSet("serve", stderr)
+	port := fs.Int("port", 9001, "企业微信消息接收服务端口")
+	dryRun, output := dryRunFlags(fs)
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "退出时等待正在执行的定时任务和 AI 回复的最长时间")
+	if code := parseFlags(fs, configFile, args); code >= 0 {
+		return code
+	}
+
+	runner := cronn.NewRunner(holiday.SystemClock{}, nil)
+	closeOutput, err := useDryRun(runner, *dryRun, *output)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	defer closeOutput()
+	setup(runner)
+	defer log.Close()
+
+	// 按配置注册定时任务（使用配置的时区，默认 Asia/Shanghai）
+	scheduler := cronn.NewScheduler(runner)
+	if err := scheduler.Apply(config.Get()); err != nil {
+		logrus.Fatal(err)
+	}
+
+	// 启动企业微信消息接收服务（在后台运行）
+	checker := health.NewChecker(scheduler)
+	http.HandleFunc("/healthz", checker.HandleHealthz)
+	http.HandleFunc("/readyz", checker.HandleReadyz)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- wecom.StartWecomServer(strconv.Itoa(*port))
+	}()
+
+	// 配置文件修改后自动重新加载，无效的配置不会生效
+	config.Watch(func(old, cfg *config.Config) {
+		if !reflect.DeepEqual(old.Log, cfg.Log) {
+			if err := log.Configure(cfg.Log); err != nil {
+				logrus.Errorf("重新配置日志失败，继续使用原配置: %v", err)
+			}
+		}
+		scheduler.Reload(old, cfg)
+	}, reloadFailed(runner.Sender))
+	if *dryRun {
+		logrus.Info("天气机器人已启动（dry-run：定时任务的消息不发送到群）")
+	} else {
+		logrus.Info("天气机器人已启动（包含定时任务和微信交互服务）")
+	}
+
+	// 运行到收到 SIGINT/SIGTERM；端口被占用等启动失败时退出，由 systemd/容器编排重启
+	ctx, stop := signalContext()
+	defer stop()
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			logrus.Error(err)
+			return exitError
+		}
+	case <-ctx.Done():
+	}
+	return shutdown(scheduler, *shutdownTimeout)
+}
+
+// shutdown 停止接收群聊消息和调度新任务，等待正在执行的定时任务和 AI 回复完成
+// 超过 timeout 时取消进行中的请求
+func shutdown(scheduler *cronn.Scheduler, timeout time.Duration) int {
+	logrus.Infof("正在退出，最多等待 %s", timeout)
+	// 先停止配置热加载，避免退出过程中重新注册定时任务
+	config.StopWatch()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = wecom.Shutdown(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		errs[1] = scheduler.Stop(ctx)
+	}()
+	wg.Wait()
+
+	if errs[0] != nil || errs[1] != nil {
+		logrus.Warnf("等待超时，已取消未完成的请求（消息服务: %v，定时任务: %v）", errs[0], errs[1])
+		return exitError
+	}
+	logrus.Info("天气机器人已退出")
+	return exitOK
+}
+
+// reloadFailed 返回配置热加载被拒绝时的处理：记录指标并通知管理员，避免配置写错后没人发现
+// sender 为空时发送到 admin.alert_webhook（dry-run 时传入写输出文件的 Sender）
+func reloadFailed(sender weather.Sender) func(error) {
+	return func(err error) {
+		metrics.ConfigReloadFailed()
+		alert := sender
+		if alert == nil {
+			alert = weather.WecomSender{Webhook: config.Get().Admin.AlertWebhook}
+		}
+		content := fmt.Sprintf("⚠️ 配置文件已修改但无效，继续使用原配置：\n%s", log.Redact(err.Error()))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := alert.Send(ctx, content, nil); err != nil {
+			logrus.Errorf("发送配置错误告警失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/log"
+)
+
+// fakeSender 记录发送的消息
+type fakeSender struct {
+	sent []string
+}
+
+func (s *fakeSender) Send(ctx context.Context, content string, mentionUsers []string) error {
+	s.sent = append(s.sent, content)
+	return nil
+}
+
+func TestReloadFailedAlerts(t *testing.T) {
+	log.SetSecrets([]string{"s3cret"})
+	defer log.SetSecrets(nil)
+
+	sender := &fakeSender{}
+	reloadFailed(sender)(errors.New("schedules.daily_report: every morning 无效，doubao_api_key: s3cret"))
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0], "继续使用原配置") || !strings.Contains(sender.sent[0], "schedules.daily_report") {
+		t.Fatalf("sent = %q", sender.sent)
+	}
+	if strings.Contains(sender.sent[0], "s3cret") {
+		t.Errorf("alert leaks secret: %q", sender.sent[0])
+	}
+}
+
+func TestReloadFailedUsesAlertWebhook(t *testing.T) {
+	var got string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text struct {
+				Content string `json:"content"`
+			} `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		got = msg.Text.Content
+		w.Write([]byte(`{"errcode":0}`))
+	}))
+	defer webhook.Close()
+
+	old := config.Get()
+	defer config.Set(old)
+	config.Set(&config.Config{WecomWebhook: "http://127.0.0.1:1/unused", Admin: config.AdminConfig{AlertWebhook: webhook.URL}})
+
+	reloadFailed(nil)(errors.New("timezone 无效"))
+	if !strings.Contains(got, "timezone 无效") {
+		t.Errorf("alert webhook got %q", got)
+	}
+}
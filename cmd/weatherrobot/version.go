@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"wechatrobot/internal/holiday"
+)
+
+// version 发布时通过 -ldflags "-X main.version=v1.2.3" 设置
+var version = "dev"
+
+// printVersion 输出版本、构建信息和内置节假日数据版本
+func printVersion(stdout io.Writer) int {
+	fmt.Fprintf(stdout, "weatherrobot %s\n", version)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		settings := make(map[string]string)
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+		if rev := settings["vcs.revision"]; rev != "" {
+			if settings["vcs.modified"] == "true" {
+				rev += "（有未提交的修改）"
+			}
+			fmt.Fprintf(stdout, "提交: %s\n", rev)
+		}
+		if t := settings["vcs.time"]; t != "" {
+			fmt.Fprintf(stdout, "提交时间: %s\n", t)
+		}
+	}
+	fmt.Fprintf(stdout, "Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	dataVersion, years := holiday.DataVersion()
+	fmt.Fprintf(stdout, "内置节假日数据: %s，覆盖年份: %v\n", dataVersion, years)
+	return exitOK
+}
@@ -2,20 +2,23 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"time"
 	"wechatrobot/internal/log"
-
-	"github.com/sirupsen/logrus"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/usage"
 )
 
 // 豆包 API 请求结构
 type DoubaoRequest struct {
-	Model string               `json:"model"`
-	Input []DoubaoInputMessage `json:"input"`
+	Model  string               `json:"model"`
+	Input  []DoubaoInputMessage `json:"input"`
+	Stream bool                 `json:"stream,omitempty"`
 }
 
 type DoubaoInputMessage struct {
@@ -38,13 +41,25 @@ type DoubaoResponse struct {
 			Text string `json:"text"`
 		} `json:"content"`
 	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
 // OpenAI API 请求结构
 type OpenAIRequest struct {
-	Model     string    `json:"model"`
-	Messages  []Message `json:"messages"`
-	MaxTokens int       `json:"max_tokens"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	MaxTokens     int            `json:"max_tokens"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions 流式请求选项，开启 IncludeUsage 后最后一个数据块会带上用量
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Message struct {
@@ -59,12 +74,36 @@ type OpenAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// OpenAI 使用的模型
+const openAIModel = "gpt-3.5-turbo"
+
+// OpenAI chat completions 接口地址，测试时替换为本地服务
+var openAIURL = "https://api.openai.com/v1/chat/completions"
+
+// Caller 标识一次调用的来源，用于用量统计
+type Caller struct {
+	User string // 群聊提问的用户
+	Job  string // 定时任务名称
+}
+
+// tokenUsage 单次调用消耗的 token
+type tokenUsage struct {
+	Prompt     int
+	Completion int
 }
 
 // GenerateOffWorkReminder 使用 AI 生成下班提醒文案
 // 会优先调用豆包（Doubao），失败则回退到 OpenAI
-// 传入参数：doubaoURL, doubaoKey, doubaoModel, openaiKey
-func GenerateOffWorkReminder(doubaoURL, doubaoKey, doubaoModel, openaiKey string) (string, error) {
+// 生成的文案会经过清理和校验（长度、违禁词），不合格时重新生成，最多 opts.MaxAttempts 次
+// 传入参数：doubaoURL, doubaoKey, doubaoModel, openaiKey, opts；ctx 取消时停止生成
+func GenerateOffWorkReminder(ctx context.Context, doubaoURL, doubaoKey, doubaoModel, openaiKey string, opts FilterOptions) (string, error) {
 	// 如果参数未传入，则尝试从环境变量读取
 	if doubaoURL == "" {
 		doubaoURL = os.Getenv("DOUBAO_URL")
@@ -82,6 +121,37 @@ func GenerateOffWorkReminder(doubaoURL, doubaoKey, doubaoModel, openaiKey string
 		openaiKey = os.Getenv("OPENAI_API_KEY")
 	}
 
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		provider, result, err := generateReminderOnce(ctx, doubaoURL, doubaoKey, doubaoModel, openaiKey)
+		if err != nil {
+			return "", err
+		}
+
+		validated, err := ValidateReminder(result, opts.BannedWords)
+		if err == nil {
+			log.From(ctx).Info(provider, " 生成的下班提醒: ", validated)
+			return validated, nil
+		}
+
+		logRejected(opts, provider, result, err)
+		lastErr = err
+		log.From(ctx).Warnf("第 %d/%d 次生成的文案未通过校验: %v", attempt, maxAttempts, err)
+	}
+
+	return "", fmt.Errorf("AI 文案连续 %d 次未通过校验: %w", maxAttempts, lastErr)
+}
+
+// 下班提醒的用量统计来源
+var offWorkCaller = Caller{Job: metrics.JobOffWork}
+
+// generateReminderOnce 调用一次 AI 生成下班提醒，返回提供者名称和原始文案
+func generateReminderOnce(ctx context.Context, doubaoURL, doubaoKey, doubaoModel, openaiKey string) (string, string, error) {
 	prompt := `生成一条有趣、温暖、鼓励的下班提醒文案。要求：
 1. 字数在30-80字之间
 2. 包含对员工的关怀和鼓励
@@ -95,30 +165,57 @@ func GenerateOffWorkReminder(doubaoURL, doubaoKey, doubaoModel, openaiKey string
 
 	// 如果配置了豆包，优先调用豆包
 	if doubaoURL != "" && doubaoKey != "" {
-		result, err := callDoubao(doubaoURL, doubaoKey, doubaoModel, prompt)
+		result, err := callDoubao(ctx, doubaoURL, doubaoKey, doubaoModel, prompt, offWorkCaller)
 		if err == nil && result != "" {
-			log.Info("Doubao 生成的下班提醒: ", result)
-			return result, nil
+			return "Doubao", result, nil
+		}
+		if ctx.Err() != nil {
+			return "", "", err
 		}
-		logrus.Warnf("Doubao 调用失败: %v，回退到 OpenAI", err)
+		log.From(ctx).Warnf("Doubao 调用失败: %v，回退到 OpenAI", err)
 	}
 
 	// 回退到 OpenAI
 	if openaiKey == "" {
-		return "", fmt.Errorf("没有可用的 AI 提供者（Doubao/OpenAI）")
+		return "", "", fmt.Errorf("没有可用的 AI 提供者（Doubao/OpenAI）")
 	}
 
-	result, err := callOpenAI(openaiKey, prompt)
+	result, err := callOpenAI(ctx, openaiKey, prompt, 150, offWorkCaller)
 	if err != nil {
-		logrus.Errorf("OpenAI 调用失败: %v", err)
-		return "", err
+		log.From(ctx).Errorf("OpenAI 调用失败: %v", err)
+		return "", "", err
 	}
-	log.Info("OpenAI 生成的下班提醒: ", result)
-	return result, nil
+	return "OpenAI", result, nil
+}
+
+// recordUsage 记录一次调用的用量
+func recordUsage(provider, model string, caller Caller, start time.Time, tokens tokenUsage, err error) {
+	usage.Add(usage.Record{
+		Time:             start,
+		Provider:         provider,
+		Model:            model,
+		User:             caller.User,
+		Job:              caller.Job,
+		PromptTokens:     tokens.Prompt,
+		CompletionTokens: tokens.Completion,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		Success:          err == nil,
+	})
+	metrics.ObserveLLM(provider, time.Since(start), tokens.Prompt, tokens.Completion, err)
 }
 
-// callDoubao 调用豆包 API
-func callDoubao(url, apiKey, model, prompt string) (string, error) {
+// callDoubao 调用豆包 API，并记录用量
+func callDoubao(ctx context.Context, url, apiKey, model, prompt string, caller Caller) (string, error) {
+	start := time.Now()
+	result, tokens, err := doDoubao(ctx, url, apiKey, model, prompt)
+	recordUsage("Doubao", model, caller, start, tokens, err)
+	return result, err
+}
+
+// doDoubao 发送豆包请求并解析回复
+func doDoubao(ctx context.Context, url, apiKey, model, prompt string) (string, tokenUsage, error) {
+	var tokens tokenUsage
+
 	requestBody := DoubaoRequest{
 		Model: model,
 		Input: []DoubaoInputMessage{
@@ -136,12 +233,12 @@ func callDoubao(url, apiKey, model, prompt string) (string, error) {
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", tokens, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", tokens, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -150,23 +247,25 @@ func callDoubao(url, apiKey, model, prompt string) (string, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("调用 Doubao API 失败: %w", err)
+		return "", tokens, fmt.Errorf("调用 Doubao API 失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", tokens, fmt.Errorf("读取响应失败: %w", err)
 	}
+	log.From(ctx).Debugf("Doubao 响应: %s", bodyBytes)
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Doubao API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+		return "", tokens, fmt.Errorf("Doubao API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var doubaoResp DoubaoResponse
 	if err := json.Unmarshal(bodyBytes, &doubaoResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return "", tokens, fmt.Errorf("解析响应失败: %w", err)
 	}
+	tokens = tokenUsage{Prompt: doubaoResp.Usage.InputTokens, Completion: doubaoResp.Usage.OutputTokens}
 
 	// 提取 output 中的内容
 	if len(doubaoResp.Output) > 0 {
@@ -174,37 +273,47 @@ func callDoubao(url, apiKey, model, prompt string) (string, error) {
 			if item.Type == "message" && len(item.Content) > 0 {
 				for _, content := range item.Content {
 					if content.Type == "output_text" && content.Text != "" {
-						return content.Text, nil
+						return content.Text, tokens, nil
 					}
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("豆包 API 返回空响应或无法解析内容")
+	return "", tokens, fmt.Errorf("豆包 API 返回空响应或无法解析内容")
 }
 
-// callOpenAI 调用 OpenAI API
-func callOpenAI(apiKey, prompt string) (string, error) {
+// callOpenAI 调用 OpenAI API，并记录用量
+func callOpenAI(ctx context.Context, apiKey, prompt string, maxTokens int, caller Caller) (string, error) {
+	start := time.Now()
+	result, tokens, err := doOpenAI(ctx, apiKey, prompt, maxTokens)
+	recordUsage("OpenAI", openAIModel, caller, start, tokens, err)
+	return result, err
+}
+
+// doOpenAI 发送 OpenAI 请求并解析回复
+func doOpenAI(ctx context.Context, apiKey, prompt string, maxTokens int) (string, tokenUsage, error) {
+	var tokens tokenUsage
+
 	requestBody := OpenAIRequest{
-		Model: "gpt-3.5-turbo",
+		Model: openAIModel,
 		Messages: []Message{
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
-		MaxTokens: 150,
+		MaxTokens: maxTokens,
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", tokens, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", tokens, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -213,148 +322,47 @@ func callOpenAI(apiKey, prompt string) (string, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("调用 OpenAI API 失败: %w", err)
+		return "", tokens, fmt.Errorf("调用 OpenAI API 失败: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", tokens, fmt.Errorf("读取响应失败: %w", err)
+	}
+	log.From(ctx).Debugf("OpenAI 响应: %s", body)
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(body))
+		return "", tokens, fmt.Errorf("OpenAI API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(body))
 	}
 
 	var openaiResp OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return "", tokens, fmt.Errorf("解析响应失败: %w", err)
 	}
+	tokens = tokenUsage{Prompt: openaiResp.Usage.PromptTokens, Completion: openaiResp.Usage.CompletionTokens}
 
 	if len(openaiResp.Choices) == 0 {
-		return "", fmt.Errorf("OpenAI 返回空响应")
+		return "", tokens, fmt.Errorf("OpenAI 返回空响应")
 	}
 
-	return openaiResp.Choices[0].Message.Content, nil
+	return openaiResp.Choices[0].Message.Content, tokens, nil
 }
 
 // AskDoubao 使用豆包 AI 回答用户问题
-func AskDoubao(question, url, apiKey, model string) (string, error) {
+func AskDoubao(ctx context.Context, question, userID, url, apiKey, model string) (string, error) {
 	if url == "" || apiKey == "" {
 		return "", fmt.Errorf("豆包配置不完整")
 	}
 
-	requestBody := DoubaoRequest{
-		Model: model,
-		Input: []DoubaoInputMessage{
-			{
-				Role: "user",
-				Content: []DoubaoContentItem{
-					{
-						Type: "input_text",
-						Text: question,
-					},
-				},
-			},
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("调用 Doubao API 失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Doubao API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var doubaoResp DoubaoResponse
-	if err := json.Unmarshal(bodyBytes, &doubaoResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	// 提取 output 中的内容
-	if len(doubaoResp.Output) > 0 {
-		for _, item := range doubaoResp.Output {
-			if item.Type == "message" && len(item.Content) > 0 {
-				for _, content := range item.Content {
-					if content.Type == "output_text" && content.Text != "" {
-						return content.Text, nil
-					}
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("豆包 API 返回空响应或无法解析内容")
+	return callDoubao(ctx, url, apiKey, model, question, Caller{User: userID})
 }
 
 // AskOpenAI 使用 OpenAI 回答用户问题
-func AskOpenAI(question, apiKey string) (string, error) {
+func AskOpenAI(ctx context.Context, question, userID, apiKey string) (string, error) {
 	if apiKey == "" {
 		return "", fmt.Errorf("OpenAI API Key 未配置")
 	}
 
-	requestBody := OpenAIRequest{
-		Model: "gpt-3.5-turbo",
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: question,
-			},
-		},
-		MaxTokens: 500,
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("调用 OpenAI API 失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API 返回错误状态码: %d, 响应: %s", resp.StatusCode, string(body))
-	}
-
-	var openaiResp OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	if len(openaiResp.Choices) == 0 {
-		return "", fmt.Errorf("OpenAI 返回空响应")
-	}
-
-	return openaiResp.Choices[0].Message.Content, nil
+	return callOpenAI(ctx, apiKey, question, 500, Caller{User: userID})
 }
@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 下班提醒文案的长度要求（按字符计），与 prompt 中的要求保持一致
+const (
+	MinReminderLength = 30
+	MaxReminderLength = 80
+)
+
+// 默认最多生成次数（首次生成 + 重新生成）
+const defaultMaxAttempts = 3
+
+// FilterOptions AI 文案校验配置
+type FilterOptions struct {
+	BannedWords     []string // 违禁词列表，命中即拒绝
+	MaxAttempts     int      // 校验失败时最多生成几次，<=0 使用默认值
+	RejectedLogFile string   // 被拒绝文案的审核日志文件（JSON Lines），为空则只写普通日志
+}
+
+var (
+	// 常见的说明性前缀，如 "文案：" "下班提醒：" "好的，以下是文案："
+	prefixPattern = regexp.MustCompile(`^(好的[，,]?\s*)?(以下是|这是|为您生成的?)?[^：:\n]{0,12}(文案|提醒|回复|内容)\s*[：:]\s*`)
+	// markdown 标题、引用、列表标记
+	linePrefixPattern = regexp.MustCompile(`^\s*(#{1,6}\s+|>\s*|[-*+]\s+|\d+[.、)]\s*)`)
+	// markdown 强调、行内代码
+	emphasisPattern = regexp.MustCompile("(\\*\\*|__|\\*|`+|~~)")
+)
+
+// 成对出现时需要去掉的引号
+var quotePairs = [][2]string{
+	{`"`, `"`},
+	{`'`, `'`},
+	{"“", "”"},
+	{"‘", "’"},
+	{"「", "」"},
+	{"『", "』"},
+	{"《", "》"},
+}
+
+// SanitizeMessage 清理 AI 返回内容：去掉说明性前缀、包裹的引号和 markdown 标记，合并为单段文字
+func SanitizeMessage(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = linePrefixPattern.ReplaceAllString(line, "")
+		line = emphasisPattern.ReplaceAllString(line, "")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	result := strings.Join(lines, "")
+
+	// 前缀和引号可能嵌套出现，如 文案："……"
+	for {
+		before := result
+		result = strings.TrimSpace(prefixPattern.ReplaceAllString(result, ""))
+		result = trimQuotes(result)
+		if result == before {
+			break
+		}
+	}
+	return result
+}
+
+// trimQuotes 去掉首尾成对的引号
+func trimQuotes(text string) string {
+	for _, pair := range quotePairs {
+		if len(text) > len(pair[0])+len(pair[1]) && strings.HasPrefix(text, pair[0]) && strings.HasSuffix(text, pair[1]) {
+			return strings.TrimSpace(text[len(pair[0]) : len(text)-len(pair[1])])
+		}
+	}
+	return text
+}
+
+// CheckBannedWords 检查文本是否包含违禁词（忽略大小写）
+func CheckBannedWords(text string, bannedWords []string) error {
+	lower := strings.ToLower(text)
+	for _, word := range bannedWords {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		if strings.Contains(lower, strings.ToLower(word)) {
+			return fmt.Errorf("包含违禁词: %s", word)
+		}
+	}
+	return nil
+}
+
+// ValidateReminder 清理并校验下班提醒文案，返回可直接发送的文案
+func ValidateReminder(text string, bannedWords []string) (string, error) {
+	cleaned := SanitizeMessage(text)
+
+	length := utf8.RuneCountInString(cleaned)
+	if length < MinReminderLength || length > MaxReminderLength {
+		return "", fmt.Errorf("文案长度 %d 不在 %d-%d 字之间", length, MinReminderLength, MaxReminderLength)
+	}
+
+	if err := CheckBannedWords(cleaned, bannedWords); err != nil {
+		return "", err
+	}
+
+	return cleaned, nil
+}
+
+// rejectedRecord 被拒绝文案的审核记录
+type rejectedRecord struct {
+	Time     string `json:"time"`
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+	Text     string `json:"text"`
+}
+
+// logRejected 记录未通过校验的文案，便于人工审核
+func logRejected(opts FilterOptions, provider, text string, reason error) {
+	logrus.WithFields(logrus.Fields{
+		"provider": provider,
+		"reason":   reason.Error(),
+		"text":     text,
+	}).Warn("AI 文案未通过校验，已拒绝")
+
+	if opts.RejectedLogFile == "" {
+		return
+	}
+
+	line, err := json.Marshal(rejectedRecord{
+		Time:     time.Now().Format(time.RFC3339),
+		Provider: provider,
+		Reason:   reason.Error(),
+		Text:     text,
+	})
+	if err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(opts.RejectedLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.Errorf("写入审核日志失败: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logrus.Errorf("写入审核日志失败: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package ai
+
+import "testing"
+
+func TestSanitizeMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Plain", "辛苦啦，早点回家", "辛苦啦，早点回家"},
+		{"Prefix", "文案：辛苦啦，早点回家", "辛苦啦，早点回家"},
+		{"Polite prefix", "好的，以下是为您生成的下班提醒文案：辛苦啦", "辛苦啦"},
+		{"Quotes", "“辛苦啦，早点回家”", "辛苦啦，早点回家"},
+		{"Prefix and quotes", "下班提醒：「辛苦啦」", "辛苦啦"},
+		{"Markdown", "**辛苦啦**，早点`回家`", "辛苦啦，早点回家"},
+		{"Heading and list", "# 下班啦\n- 辛苦啦", "下班啦辛苦啦"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeMessage(tt.input); got != tt.want {
+				t.Errorf("SanitizeMessage(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReminder(t *testing.T) {
+	valid := "忙碌了一整天，辛苦啦！晚风正好，落日正美，关上电脑去拥抱属于你的温柔夜晚吧，明天见！"
+	tests := []struct {
+		name    string
+		input   string
+		banned  []string
+		wantErr bool
+	}{
+		{"Valid", valid, nil, false},
+		{"Valid with prefix", "文案：" + valid, nil, false},
+		{"Too short", "下班啦！", nil, true},
+		{"Too long", valid + valid + valid, nil, true},
+		{"Banned word", valid, []string{"电脑"}, true},
+		{"Banned word case insensitive", valid + "KPI", []string{"kpi"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateReminder(tt.input, tt.banned)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateReminder(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
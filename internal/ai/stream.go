@@ -0,0 +1,259 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 单行 SSE 数据的最大长度
+const maxSSELineSize = 1024 * 1024
+
+// readSSE 逐条读取 SSE 事件的 data 字段，handle 返回 true 表示流已结束
+func readSSE(r io.Reader, handle func(data []byte) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		// 空行表示一个事件结束
+		if len(line) == 0 {
+			if data.Len() == 0 {
+				continue
+			}
+			done, err := handle(data.Bytes())
+			data.Reset()
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+
+		if bytes.HasPrefix(line, []byte("data:")) {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimSpace(line[len("data:"):]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	// 流结束时没有以空行收尾
+	if data.Len() > 0 {
+		_, err := handle(data.Bytes())
+		return err
+	}
+	return nil
+}
+
+// doubaoStreamEvent 豆包 Responses API 流式事件
+type doubaoStreamEvent struct {
+	Type     string `json:"type"`
+	Delta    string `json:"delta"`
+	Response struct {
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"response"`
+}
+
+// openAIStreamChunk OpenAI chat completions 流式数据块
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// StreamDoubao 以流式方式调用豆包回答用户问题
+// 每收到一段增量文本调用一次 onDelta（可为 nil），返回完整回复；ctx 取消时返回已收到的部分内容和错误
+func StreamDoubao(ctx context.Context, question, userID, url, apiKey, model string, onDelta func(string)) (string, error) {
+	if url == "" || apiKey == "" {
+		return "", fmt.Errorf("豆包配置不完整")
+	}
+
+	start := time.Now()
+	result, tokens, err := streamDoubao(ctx, url, apiKey, model, question, onDelta)
+	recordUsage("Doubao", model, Caller{User: userID}, start, tokens, err)
+	return result, err
+}
+
+func streamDoubao(ctx context.Context, url, apiKey, model, prompt string, onDelta func(string)) (string, tokenUsage, error) {
+	var tokens tokenUsage
+
+	requestBody := DoubaoRequest{
+		Model: model,
+		Input: []DoubaoInputMessage{
+			{
+				Role: "user",
+				Content: []DoubaoContentItem{
+					{
+						Type: "input_text",
+						Text: prompt,
+					},
+				},
+			},
+		},
+		Stream: true,
+	}
+
+	resp, err := postStream(ctx, url, apiKey, requestBody)
+	if err != nil {
+		return "", tokens, fmt.Errorf("调用 Doubao API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result strings.Builder
+	err = readSSE(resp.Body, func(data []byte) (bool, error) {
+		var event doubaoStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return false, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+
+		switch event.Type {
+		case "response.output_text.delta":
+			result.WriteString(event.Delta)
+			if onDelta != nil {
+				onDelta(event.Delta)
+			}
+		case "response.completed":
+			tokens = tokenUsage{Prompt: event.Response.Usage.InputTokens, Completion: event.Response.Usage.OutputTokens}
+			return true, nil
+		case "response.failed", "error":
+			if event.Response.Error != nil {
+				return true, fmt.Errorf("豆包流式响应失败: %s", event.Response.Error.Message)
+			}
+			return true, fmt.Errorf("豆包流式响应失败: %s", string(data))
+		}
+		return false, nil
+	})
+	if err != nil {
+		return result.String(), tokens, err
+	}
+	if result.Len() == 0 {
+		return "", tokens, fmt.Errorf("豆包 API 返回空响应或无法解析内容")
+	}
+	return result.String(), tokens, nil
+}
+
+// StreamOpenAI 以流式方式调用 OpenAI 回答用户问题，用法同 StreamDoubao
+func StreamOpenAI(ctx context.Context, question, userID, apiKey string, onDelta func(string)) (string, error) {
+	if apiKey == "" {
+		return "", fmt.Errorf("OpenAI API Key 未配置")
+	}
+
+	start := time.Now()
+	result, tokens, err := streamOpenAI(ctx, apiKey, question, onDelta)
+	recordUsage("OpenAI", openAIModel, Caller{User: userID}, start, tokens, err)
+	return result, err
+}
+
+func streamOpenAI(ctx context.Context, apiKey, prompt string, onDelta func(string)) (string, tokenUsage, error) {
+	var tokens tokenUsage
+
+	requestBody := OpenAIRequest{
+		Model: openAIModel,
+		Messages: []Message{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		MaxTokens:     500,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	resp, err := postStream(ctx, openAIURL, apiKey, requestBody)
+	if err != nil {
+		return "", tokens, fmt.Errorf("调用 OpenAI API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result strings.Builder
+	done := false
+	err = readSSE(resp.Body, func(data []byte) (bool, error) {
+		if string(data) == "[DONE]" {
+			done = true
+			return true, nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return false, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if chunk.Usage != nil {
+			tokens = tokenUsage{Prompt: chunk.Usage.PromptTokens, Completion: chunk.Usage.CompletionTokens}
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			result.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return result.String(), tokens, err
+	}
+	// 连接在 [DONE] 之前断开，回答可能不完整
+	if !done {
+		return result.String(), tokens, fmt.Errorf("OpenAI 流式响应在 [DONE] 之前中断")
+	}
+	if result.Len() == 0 {
+		return "", tokens, fmt.Errorf("OpenAI 返回空响应")
+	}
+	return result.String(), tokens, nil
+}
+
+// postStream 发送流式请求，状态码非 200 时返回错误
+func postStream(ctx context.Context, url, apiKey string, body interface{}) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("返回错误状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+	}
+	return resp, nil
+}
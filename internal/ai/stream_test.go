@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamDoubao(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: response.created\ndata: {\"type\":\"response.created\"}\n\n")
+		fmt.Fprint(w, "event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"今天\"}\n\n")
+		fmt.Fprint(w, "event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"晴天\"}\n\n")
+		fmt.Fprint(w, "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"usage\":{\"input_tokens\":5,\"output_tokens\":2}}}\n\n")
+	}))
+	defer server.Close()
+
+	var deltas []string
+	result, tokens, err := streamDoubao(context.Background(), server.URL, "key", "model", "天气", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("streamDoubao error: %v", err)
+	}
+	if result != "今天晴天" {
+		t.Errorf("result = %q, want 今天晴天", result)
+	}
+	if len(deltas) != 2 {
+		t.Errorf("got %d deltas, want 2", len(deltas))
+	}
+	if tokens.Prompt != 5 || tokens.Completion != 2 {
+		t.Errorf("tokens = %+v, want 5/2", tokens)
+	}
+}
+
+func TestStreamDoubaoCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"type\":\"response.output_text.delta\",\"delta\":\"部分\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, _, err := streamDoubao(ctx, server.URL, "key", "model", "天气", func(string) { cancel() })
+	if err == nil {
+		t.Fatal("expected error after cancellation")
+	}
+	if result != "部分" {
+		t.Errorf("partial result = %q, want 部分", result)
+	}
+}
+
+// useOpenAIServer 把 OpenAI 接口地址替换为本地服务
+func useOpenAIServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	old := openAIURL
+	openAIURL = server.URL
+	t.Cleanup(func() {
+		openAIURL = old
+		server.Close()
+	})
+}
+
+func TestStreamOpenAI(t *testing.T) {
+	useOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"今天\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"晴天\"}}]}\n\n")
+		// include_usage 时最后一个数据块没有 choices，只有用量
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		// [DONE] 之后的内容不再读取
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"多余\"}}]}\n\n")
+	})
+
+	var deltas []string
+	result, tokens, err := streamOpenAI(context.Background(), "key", "天气", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("streamOpenAI error: %v", err)
+	}
+	if result != "今天晴天" {
+		t.Errorf("result = %q, want 今天晴天", result)
+	}
+	if len(deltas) != 2 {
+		t.Errorf("got %d deltas, want 2", len(deltas))
+	}
+	if tokens.Prompt != 5 || tokens.Completion != 2 {
+		t.Errorf("tokens = %+v, want 5/2", tokens)
+	}
+}
+
+func TestStreamOpenAITruncated(t *testing.T) {
+	for name, tail := range map[string]string{
+		"mid-chunk":    "data: {\"choices\":[{\"delta\":{\"con", // 连接在数据块中间断开
+		"without DONE": "",                                      // 连接在 [DONE] 之前断开
+	} {
+		useOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"部分\"}}]}\n\n")
+			fmt.Fprint(w, tail)
+		})
+
+		result, _, err := streamOpenAI(context.Background(), "key", "天气", nil)
+		if err == nil {
+			t.Errorf("%s: expected error for a truncated stream", name)
+		}
+		if result != "部分" {
+			t.Errorf("%s: partial result = %q, want 部分", name, result)
+		}
+	}
+}
+
+func TestStreamOpenAICancelled(t *testing.T) {
+	useOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"部分\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, _, err := streamOpenAI(ctx, "key", "天气", func(string) { cancel() })
+	if err == nil {
+		t.Fatal("expected error after cancellation")
+	}
+	if result != "部分" {
+		t.Errorf("partial result = %q, want 部分", result)
+	}
+}
@@ -1,13 +1,22 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"wechatrobot/internal/history"
 	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/usage"
 )
 
-var Cfg Config
-
 type Config struct {
 	WecomWebhook    string           `mapstructure:"wecom_webhook"`
 	WeatherAPIKey   string           `mapstructure:"weather_api_key"`
@@ -19,20 +28,269 @@ type Config struct {
 	DoubaoModel     string           `mapstructure:"doubao_model"`
 	MentionUsers    []string         `mapstructure:"mention_users"`
 	OffWorkMessages []string         `mapstructure:"off_work_messages"`
+	BannedWords     []string         `mapstructure:"banned_words"`    // AI 文案违禁词
+	AIMaxAttempts   int              `mapstructure:"ai_max_attempts"` // AI 文案校验失败时最多生成次数
+	AIRejectedLog   string           `mapstructure:"ai_rejected_log"` // 被拒绝 AI 文案的审核日志文件
+	AIUsage         usage.Config     `mapstructure:"ai_usage"`        // LLM 用量统计与每日预算
+	Timezone        string           `mapstructure:"timezone"`        // 判断日期和执行定时任务的时区（IANA 名称）
 	Holidays        []holiday.Holiday `mapstructure:"holidays"` // 用户自定义假期
+	HolidayData     holiday.DataConfig `mapstructure:"holiday_data"` // 法定节假日数据来源
+	HolidayICS      []string         `mapstructure:"holiday_ics"`     // 从 ICS 日历（本地路径或 URL）导入的假期
+	Leave           holiday.LeaveConfig `mapstructure:"leave"`        // 个人请假，请假期间定时消息不 @ 该用户
+	Calendars       []holiday.CalendarConfig `mapstructure:"calendars"` // 自定义日历（地区节假日、公司假期）
+	Groups          []GroupConfig    `mapstructure:"groups"`          // 接收定时消息的群，为空时使用顶层配置
+	SpecialDays     holiday.SpecialDaysConfig `mapstructure:"special_days"` // 节日倒计时、节气、生日和入职周年
+	Schedules       ScheduleConfig   `mapstructure:"schedules"`       // 定时任务的 cron 表达式
+	ChatLimit       ChatLimitConfig  `mapstructure:"chat_limit"`      // 群聊提问限流
+	Dedup           DedupConfig      `mapstructure:"dedup"`           // 回调消息去重
+	Chat            ChatConfig       `mapstructure:"chat"`            // 群聊问答
+	Festival        FestivalConfig   `mapstructure:"festival"`        // 节假日前后的消息
+	Secrets         SecretsConfig    `mapstructure:"secrets"`         // 加密密钥的解密方式
+	Health          HealthConfig     `mapstructure:"health"`          // 健康检查
+	Admin           AdminConfig      `mapstructure:"admin"`           // /preview、/history、/usage、/chat/stats 等管理接口的访问控制
+	Log             log.Config       `mapstructure:"log"`             // 日志级别、格式和文件轮转
+	History         history.Config   `mapstructure:"history"`         // 定时任务执行记录
+}
+
+// GroupConfig 一个接收定时消息的群，各群可以使用不同的日历组合
+type GroupConfig struct {
+	Name         string   `mapstructure:"name"`          // 群名称，用于日志
+	Webhook      string   `mapstructure:"webhook"`       // 群机器人 webhook，为空时使用 wecom_webhook
+	Calendars    []string `mapstructure:"calendars"`     // 组合使用的日历，如 ["hk", "company"]，为空时使用 cn-mainland
+	Locations    []string `mapstructure:"locations"`     // 天气城市，为空时使用顶层 locations
+	MentionUsers []string `mapstructure:"mention_users"` // 提及的用户，为空时使用顶层 mention_users
+}
+
+// EffectiveGroups 返回接收定时消息的群，未配置 groups 时返回由顶层配置组成的默认群
+func (c *Config) EffectiveGroups() []GroupConfig {
+	if len(c.Groups) == 0 {
+		return []GroupConfig{c.fillGroup(GroupConfig{Name: "default"})}
+	}
+	groups := make([]GroupConfig, len(c.Groups))
+	for i, g := range c.Groups {
+		groups[i] = c.fillGroup(g)
+	}
+	return groups
+}
+
+// fillGroup 用顶层配置补全群配置中的空字段
+func (c *Config) fillGroup(g GroupConfig) GroupConfig {
+	if g.Webhook == "" {
+		g.Webhook = c.WecomWebhook
+	}
+	if len(g.Calendars) == 0 {
+		g.Calendars = []string{holiday.DefaultCalendar}
+	}
+	if len(g.Locations) == 0 {
+		g.Locations = c.Locations
+	}
+	if len(g.MentionUsers) == 0 {
+		g.MentionUsers = c.MentionUsers
+	}
+	return g
+}
+
+// FestivalConfig 节假日前后的消息配置，节日问候只在假期第一天发送
+type FestivalConfig struct {
+	PreHoliday  bool `mapstructure:"pre_holiday"`  // 节前最后一个工作日在天气报告中提醒即将放假
+	WelcomeBack bool `mapstructure:"welcome_back"` // 节后第一天发送欢迎回来和假期天气回顾
+}
+
+// ChatConfig 群聊问答配置
+type ChatConfig struct {
+	Stream      bool          `mapstructure:"stream"`       // 使用流式接口调用 AI
+	ThinkingAck bool          `mapstructure:"thinking_ack"` // 调用 AI 前先回复 "思考中…"
+	Timeout     time.Duration `mapstructure:"timeout"`      // 单次回答的总超时
+}
+
+// DedupConfig 回调消息去重配置，企业微信未及时收到响应时会重试回调
+type DedupConfig struct {
+	TTL      time.Duration `mapstructure:"ttl"`      // 消息 ID 保留时长
+	Capacity int           `mapstructure:"capacity"` // 最多保留的消息 ID 数
+	File     string        `mapstructure:"file"`     // 持久化文件，为空则只保存在内存中
+}
+
+// HealthConfig /readyz 的依赖检查配置
+type HealthConfig struct {
+	ActiveChecks bool          `mapstructure:"active_checks"` // 每次 /readyz 都主动检查和风天气、企业微信和 LLM 的连通性，否则只在请求带 ?active=1 时检查
+	Timeout      time.Duration `mapstructure:"timeout"`       // 每项检查的超时时间
+	CacheTTL     time.Duration `mapstructure:"cache_ttl"`     // 检查结果的缓存时间，避免频繁探测消耗接口额度
+}
+
+// AdminConfig 管理接口的访问控制和给管理员的告警，管理接口与企业微信回调共用端口
+type AdminConfig struct {
+	Token        string `mapstructure:"token"`         // 请求头 Authorization: Bearer <token>；为空时只允许本机访问
+	AlertWebhook string `mapstructure:"alert_webhook"` // 配置热加载失败等告警发送到的群机器人 webhook，为空时发送到 wecom_webhook
+}
+
+// ChatLimitConfig 群聊提问限流配置
+type ChatLimitConfig struct {
+	UserPerMinute   int `mapstructure:"user_per_minute"`   // 每个用户每分钟最多提问次数，0 表示不限制
+	UserBurst       int `mapstructure:"user_burst"`        // 每个用户允许的突发提问次数
+	GlobalPerMinute int `mapstructure:"global_per_minute"` // 全局每分钟最多处理的提问数，0 表示不限制
+	GlobalBurst     int `mapstructure:"global_burst"`      // 全局允许的突发提问数
+	NoticePerMinute int `mapstructure:"notice_per_minute"` // 所有用户每分钟最多收到的限流提示数，0 表示不限制
+	Workers         int `mapstructure:"workers"`           // 并发处理提问的 worker 数
+	QueueSize       int `mapstructure:"queue_size"`        // 等待处理的提问队列长度
+}
+
+// 当前生效的配置，热加载时整体替换，读取方通过 Get 拿到一致的快照
+var current atomic.Value
+
+// Get 返回当前生效的配置，不要修改返回值
+func Get() *Config {
+	if c, ok := current.Load().(*Config); ok {
+		return c
+	}
+	return &Config{}
 }
 
+// Set 替换当前生效的配置，并更新日志中需要隐藏的密钥
+func Set(c *Config) {
+	current.Store(c)
+	log.SetSecrets(c.SecretValues())
+}
+
+// 配置文件路径，为空时使用 ./config/config.yaml
+var configFile string
+
+// SetFile 指定配置文件路径，需要在 Load/Check 之前调用
+func SetFile(file string) {
+	configFile = file
+}
+
+// newViper 创建读取配置文件的 viper 实例并设置默认值
+func newViper() *viper.Viper {
+	v := viper.New()
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		v.SetConfigName("config")
+		v.AddConfigPath("./config")
+	}
+	v.SetConfigType("yaml")
+
+	// 环境变量覆盖配置项，嵌套的键用下划线连接，如 CHAT_LIMIT_WORKERS 覆盖 chat_limit.workers
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// 配置文件中没有的键不会被 AutomaticEnv 读取，密钥需要显式绑定
+	for _, key := range secretKeys {
+		v.BindEnv(key)
+	}
+
+	// 默认值
+	v.SetDefault("timezone", holiday.DefaultTimezone)
+	v.SetDefault("schedules.daily_report", "0 8 * * *")
+	v.SetDefault("schedules.off_work", "0 18 * * *")
+	v.SetDefault("schedules.refresh_calendars", "30 7 * * *")
+	v.SetDefault("chat_limit.user_per_minute", 3)
+	v.SetDefault("chat_limit.user_burst", 2)
+	v.SetDefault("chat_limit.global_per_minute", 10)
+	v.SetDefault("chat_limit.global_burst", 5)
+	v.SetDefault("chat_limit.notice_per_minute", 3)
+	v.SetDefault("chat_limit.workers", 2)
+	v.SetDefault("chat_limit.queue_size", 20)
+	v.SetDefault("chat.stream", true)
+	v.SetDefault("chat.thinking_ack", true)
+	v.SetDefault("chat.timeout", "60s")
+	v.SetDefault("festival.pre_holiday", true)
+	v.SetDefault("festival.welcome_back", true)
+	v.SetDefault("holiday_data.cache_dir", "data/holiday-cache")
+	v.SetDefault("dedup.ttl", "10m")
+	v.SetDefault("dedup.capacity", 10000)
+	v.SetDefault("health.timeout", "5s")
+	v.SetDefault("health.cache_ttl", "1m")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "text")
+	v.SetDefault("log.max_size_mb", 100)
+	v.SetDefault("log.max_age_days", 30)
+	v.SetDefault("history.retention_days", 90)
+	v.SetDefault("ai_usage.retention_days", 90)
+	v.SetDefault("leave.show_in_report", true)
+	v.SetDefault("special_days.countdown.max_days", 30)
+	return v
+}
+
+// read 读取、解析并校验配置文件，配置有问题时返回 *ValidationError
+func read(v *viper.Viper) (*Config, error) {
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	if err := decryptSops(v); err != nil {
+		return nil, err
+	}
+
+	// 解析出错的字段保持零值，继续校验其他字段，一次报告所有问题
+	settings := v.AllSettings()
+	problems := resolveSecrets(settings)
+	var cfg Config
+	if err := decode(settings, &cfg); err != nil {
+		var decodeErr *mapstructure.Error
+		if !errors.As(err, &decodeErr) {
+			return nil, fmt.Errorf("解析配置失败: %w", err)
+		}
+		problems = append(problems, decodeProblems(decodeErr)...)
+	}
+	problems = mergeProblems(problems, cfg.Validate())
+	if len(problems) > 0 {
+		return nil, &ValidationError{File: v.ConfigFileUsed(), Problems: problems}
+	}
+	return &cfg, nil
+}
+
+// Check 读取并校验配置文件，不影响当前生效的配置
+func Check() (*Config, error) {
+	return read(newViper())
+}
+
+// Load 加载配置，配置无效时退出
 func Load() {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./config")
-	viper.AutomaticEnv()
+	v := newViper()
+	cfg, err := read(v)
+	if err != nil {
+		logrus.Fatal(err)
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		logrus.Fatal("读取配置文件失败: ", err)
+	loc, _ := time.LoadLocation(cfg.Timezone)
+	holiday.SetLocation(loc)
+	Set(cfg)
+	watcher = v
+}
+
+// decode 按 viper.Unmarshal 的方式解析配置
+func decode(settings map[string]interface{}, cfg *Config) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           cfg,
+		WeaklyTypedInput: true,
+		DecodeHook:       decodeHook,
+	})
+	if err != nil {
+		return err
 	}
+	return decoder.Decode(settings)
+}
 
-	if err := viper.Unmarshal(&Cfg); err != nil {
-		logrus.Fatal("解析配置失败: ", err)
+// decodeHook 在 viper 默认的时长、逗号分隔列表转换之外，把日期字符串解析为 holiday.Date
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	stringToDateHookFunc,
+)
+
+var dateType = reflect.TypeOf(holiday.Date{})
+
+// stringToDateHookFunc 解析 YYYY-MM-DD，日期无效时报错而不是静默忽略
+func stringToDateHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if t != dateType {
+		return data, nil
+	}
+	switch v := data.(type) {
+	case string:
+		return holiday.ParseDate(v)
+	case time.Time:
+		// YAML 中未加引号的日期可能被解析为时间
+		return holiday.Date{Year: v.Year(), Month: v.Month(), Day: v.Day()}, nil
 	}
+	return data, nil
 }
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ScheduleConfig 定时任务的 cron 表达式（分 时 日 月 周），在配置的时区执行
+type ScheduleConfig struct {
+	DailyReport      string `mapstructure:"daily_report"`      // 天气报告
+	OffWork          string `mapstructure:"off_work"`          // 下班提醒
+	RefreshCalendars string `mapstructure:"refresh_calendars"` // 刷新自定义日历、ICS 假期和请假
+}
+
+// 修改后需要重启才能生效的配置项
+var restartOnly = map[string]bool{
+	"timezone":   true,
+	"ai_usage":   true,
+	"chat_limit": true,
+	"dedup":      true,
+	"history":    true,
+}
+
+// Load 读取配置时使用的 viper 实例，Watch 监听它对应的文件
+var watcher *viper.Viper
+
+// viper 无法停止监听，StopWatch 之后文件变化不再处理；watchMu 保证 StopWatch 返回时没有正在执行的 onChange
+var (
+	watchMu      sync.Mutex
+	watchStopped bool
+)
+
+// Watch 监听配置文件，文件变化时重新读取和校验
+// 新配置有效时替换当前配置并调用 onChange，无效时保留旧配置并调用 onError
+func Watch(onChange func(old, cfg *Config), onError func(error)) {
+	if watcher == nil {
+		logrus.Warn("配置尚未加载，无法监听配置文件")
+		return
+	}
+
+	watcher.OnConfigChange(func(fsnotify.Event) {
+		watchMu.Lock()
+		defer watchMu.Unlock()
+		if watchStopped {
+			return
+		}
+
+		cfg, err := read(newViper())
+		if err != nil {
+			logrus.Errorf("配置文件已修改但无效，继续使用原配置: %v", err)
+			onError(err)
+			return
+		}
+
+		old := Get()
+		changed := Diff(old, cfg)
+		if len(changed) == 0 {
+			return
+		}
+
+		if pending := keepRestartOnly(old, cfg); len(pending) > 0 {
+			logrus.Warnf("配置项 %s 需要重启后生效", strings.Join(pending, ", "))
+			if changed = Diff(old, cfg); len(changed) == 0 {
+				return
+			}
+		}
+
+		Set(cfg)
+		logrus.Infof("配置已重新加载，变更: %s", strings.Join(changed, ", "))
+		onChange(old, cfg)
+	})
+	watcher.WatchConfig()
+	logrus.Infof("正在监听配置文件 %s", watcher.ConfigFileUsed())
+}
+
+// StopWatch 停止处理配置文件变化，等待正在执行的重新加载完成，退出前调用
+func StopWatch() {
+	watchMu.Lock()
+	watchStopped = true
+	watchMu.Unlock()
+}
+
+// Diff 返回两份配置中取值不同的顶层配置项
+func Diff(old, cfg *Config) []string {
+	var changed []string
+	ov, nv := reflect.ValueOf(*old), reflect.ValueOf(*cfg)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, t.Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return changed
+}
+
+// keepRestartOnly 把需要重启才能生效的配置项恢复为旧值，返回被恢复的配置项
+func keepRestartOnly(old, cfg *Config) []string {
+	var kept []string
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if restartOnly[key] && !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			nv.Field(i).Set(ov.Field(i))
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+wecom_webhook: "https://example.com/webhook"
+weather_api_key: "test-key"
+locations: ["101010100"]
+off_work_messages: ["下班啦"]
+mention_users: ["@all"]
+timezone: "Asia/Shanghai"
+`
+
+// writeConfig 先写临时文件再重命名，避免监听到写了一半的文件
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	file := filepath.Join(dir, "config", "config.yaml")
+	if err := os.WriteFile(file+".tmp", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useConfigDir 切换到临时目录，写入 config/config.yaml
+func useConfigDir(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, content)
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestWatch(t *testing.T) {
+	dir := useConfigDir(t, testConfig)
+	Load()
+	if got := Get().Schedules.DailyReport; got != "0 8 * * *" {
+		t.Fatalf("default schedules.daily_report = %q", got)
+	}
+
+	changes := make(chan []string, 10)
+	errs := make(chan error, 10)
+	Watch(func(old, cfg *Config) { changes <- Diff(old, cfg) }, func(err error) { errs <- err })
+
+	// 无效的配置保留原配置
+	writeConfig(t, dir, testConfig+"schedules:\n  daily_report: \"every morning\"\n")
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "schedules.daily_report") {
+			t.Errorf("error = %v, want schedules.daily_report", err)
+		}
+	case <-changes:
+		t.Fatal("invalid config was applied")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	if got := Get().Schedules.DailyReport; got != "0 8 * * *" {
+		t.Errorf("schedules.daily_report after invalid reload = %q, want unchanged", got)
+	}
+
+	// 时区需要重启才能生效，其他变更立即生效
+	content := strings.NewReplacer("Asia/Shanghai", "Asia/Hong_Kong", "101010100", "101020100").Replace(testConfig)
+	writeConfig(t, dir, content)
+	select {
+	case changed := <-changes:
+		if !reflect.DeepEqual(changed, []string{"locations"}) {
+			t.Errorf("changed = %v, want [locations]", changed)
+		}
+		if got := Get(); got.Locations[0] != "101020100" || got.Timezone != "Asia/Shanghai" {
+			t.Errorf("config after reload: locations %v, timezone %s", got.Locations, got.Timezone)
+		}
+	case err := <-errs:
+		t.Fatalf("valid config rejected: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Config{Locations: []string{"101010100"}, Timezone: "Asia/Shanghai"}
+	cfg := &Config{Locations: []string{"101010100"}, Timezone: "UTC", Schedules: ScheduleConfig{OffWork: "0 19 * * *"}}
+	if got := Diff(old, cfg); !reflect.DeepEqual(got, []string{"timezone", "schedules"}) {
+		t.Errorf("Diff = %v", got)
+	}
+	if got := keepRestartOnly(old, cfg); !reflect.DeepEqual(got, []string{"timezone"}) || cfg.Timezone != "Asia/Shanghai" {
+		t.Errorf("keepRestartOnly = %v, timezone %s", got, cfg.Timezone)
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 配置中的密钥可以不写明文：
+//   - "${ENV_NAME}" 引用环境变量，可以出现在任意字符串中
+//   - "<key>_file: /run/secrets/xxx" 从文件读取（Docker/K8s secrets），文件末尾的换行会被去掉
+//   - 以 "-----BEGIN AGE ENCRYPTED FILE-----" 开头的值用 age 解密，私钥为 secrets.age_identity
+//   - 整个配置文件用 sops 加密时（含顶层 sops 字段），读取时调用 sops 解密
+
+// SecretsConfig 密钥解密配置
+type SecretsConfig struct {
+	AgeIdentity string `mapstructure:"age_identity"` // age 私钥文件路径，为空时使用环境变量 AGE_IDENTITY_FILE
+}
+
+// 可以通过同名大写环境变量（如 WEATHER_API_KEY）直接提供的密钥
+var secretKeys = []string{"wecom_webhook", "weather_api_key", "openai_api_key", "doubao_api_key"}
+
+const ageHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// runCommand 执行外部解密命令，测试时替换
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// decryptSops 配置文件用 sops 加密时，用解密后的内容替换
+func decryptSops(v *viper.Viper) error {
+	if !v.IsSet("sops") {
+		return nil
+	}
+	out, err := runCommand(nil, "sops", "--decrypt", "--output-type", "yaml", v.ConfigFileUsed())
+	if err != nil {
+		return fmt.Errorf("解密配置文件失败: %w", err)
+	}
+	if err := v.ReadConfig(bytes.NewReader(out)); err != nil {
+		return fmt.Errorf("解析解密后的配置失败: %w", err)
+	}
+	return nil
+}
+
+// secretResolver 在解析配置前替换环境变量引用、*_file 和加密值
+type secretResolver struct {
+	identity string
+	problems []Problem
+}
+
+// resolveSecrets 处理 viper 读出的配置，返回无法解析的引用
+func resolveSecrets(settings map[string]interface{}) []Problem {
+	r := &secretResolver{identity: os.Getenv("AGE_IDENTITY_FILE")}
+	if secrets, ok := settings["secrets"].(map[string]interface{}); ok {
+		if file, ok := secrets["age_identity"].(string); ok && file != "" {
+			r.identity = r.expand("secrets.age_identity", file)
+		}
+	}
+	r.resolveMap("", settings)
+	sort.SliceStable(r.problems, func(i, j int) bool { return r.problems[i].Field < r.problems[j].Field })
+	return r.problems
+}
+
+func (r *secretResolver) add(field, format string, args ...interface{}) {
+	r.problems = append(r.problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolveMap 处理一层配置；YAML 列表中的对象是 map[interface{}]interface{}，统一转成字符串键
+func (r *secretResolver) resolveMap(path string, m map[string]interface{}) {
+	var fileKeys []string
+	for key := range m {
+		if strings.HasSuffix(key, "_file") {
+			fileKeys = append(fileKeys, key)
+		}
+	}
+	sort.Strings(fileKeys)
+	for _, key := range fileKeys {
+		base := strings.TrimSuffix(key, "_file")
+		file, ok := m[key].(string)
+		if !ok {
+			continue
+		}
+		delete(m, key)
+		field := join(path, key)
+		if existing, ok := m[base].(string); ok && existing != "" {
+			r.add(field, "不能同时配置 %s 和 %s", base, key)
+			continue
+		}
+		b, err := os.ReadFile(r.expand(field, file))
+		if err != nil {
+			r.add(field, "读取密钥文件失败: %v", err)
+			continue
+		}
+		m[base] = strings.TrimRight(string(b), "\r\n")
+	}
+	for key, value := range m {
+		m[key] = r.resolve(join(path, key), value)
+	}
+}
+
+func (r *secretResolver) resolve(field string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(field, v)
+	case map[string]interface{}:
+		r.resolveMap(field, v)
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[strings.ToLower(fmt.Sprint(k))] = val
+		}
+		r.resolveMap(field, m)
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.resolve(fmt.Sprintf("%s[%d]", field, i), item)
+		}
+		return v
+	}
+	return value
+}
+
+func (r *secretResolver) resolveString(field, s string) string {
+	s = r.expand(field, s)
+	if !strings.HasPrefix(strings.TrimSpace(s), ageHeader) {
+		return s
+	}
+	if r.identity == "" {
+		r.add(field, "值已用 age 加密，但未配置 secrets.age_identity 或环境变量 AGE_IDENTITY_FILE")
+		return ""
+	}
+	out, err := runCommand([]byte(s), "age", "--decrypt", "--identity", r.identity)
+	if err != nil {
+		r.add(field, "age 解密失败: %v", err)
+		return ""
+	}
+	return strings.TrimRight(string(out), "\r\n")
+}
+
+// expand 替换 ${ENV} 引用，未设置的环境变量记为问题
+func (r *secretResolver) expand(field, s string) string {
+	return envRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			r.add(field, "环境变量 %s 未设置", name)
+		}
+		return value
+	})
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// SecretValues 返回配置中的密钥，用于在日志中隐藏
+// webhook 地址中的 key 参数也单独返回，日志中只出现 key 时同样会被隐藏
+func (c *Config) SecretValues() []string {
+	var secrets []string
+	add := func(s string) {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	addWebhook := func(webhook string) {
+		add(webhook)
+		if u, err := url.Parse(webhook); err == nil {
+			add(u.Query().Get("key"))
+		}
+	}
+
+	addWebhook(c.WecomWebhook)
+	addWebhook(c.Admin.AlertWebhook)
+	for _, g := range c.Groups {
+		addWebhook(g.Webhook)
+	}
+	add(c.WeatherAPIKey)
+	add(c.OpenAIAPIKey)
+	add(c.DoubaoAPIKey)
+	add(c.Admin.Token)
+	return secrets
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "qweather")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WEBHOOK_KEY", "env-key")
+	t.Setenv("SECRETS_DIR", dir)
+
+	var decrypted []string
+	runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+		decrypted = append(decrypted, name+" "+strings.Join(args, " "))
+		return []byte("age-key\n"), nil
+	}
+	defer func() { runCommand = defaultRunCommand }()
+
+	useConfigDir(t, `
+locations: ["101010100"]
+off_work_messages: ["下班啦"]
+wecom_webhook: "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=${WEBHOOK_KEY}"
+weather_api_key_file: "${SECRETS_DIR}/qweather"
+doubao_url: "https://example.com/doubao"
+doubao_model: "model"
+doubao_api_key: |
+  -----BEGIN AGE ENCRYPTED FILE-----
+  YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSB0ZXN0
+  -----END AGE ENCRYPTED FILE-----
+secrets:
+  age_identity: "/keys/age.txt"
+`)
+	cfg, err := Check()
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if cfg.WecomWebhook != "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=env-key" {
+		t.Errorf("wecom_webhook = %q", cfg.WecomWebhook)
+	}
+	if cfg.WeatherAPIKey != "file-key" {
+		t.Errorf("weather_api_key = %q, want file-key", cfg.WeatherAPIKey)
+	}
+	if cfg.DoubaoAPIKey != "age-key" || !reflect.DeepEqual(decrypted, []string{"age --decrypt --identity /keys/age.txt"}) {
+		t.Errorf("doubao_api_key = %q, commands %v", cfg.DoubaoAPIKey, decrypted)
+	}
+
+	want := []string{cfg.WecomWebhook, "env-key", "file-key", "age-key"}
+	if got := cfg.SecretValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SecretValues = %v, want %v", got, want)
+	}
+}
+
+func TestResolveSecretsProblems(t *testing.T) {
+	os.Unsetenv("MISSING_WEBHOOK")
+	useConfigDir(t, `
+wecom_webhook: "${MISSING_WEBHOOK}"
+weather_api_key: "key"
+weather_api_key_file: "/run/secrets/qweather"
+locations: ["101010100"]
+off_work_messages: ["下班啦"]
+`)
+	_, err := Check()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Check() error = %v, want *ValidationError", err)
+	}
+	want := []Problem{
+		{Field: "weather_api_key_file", Message: "不能同时配置 weather_api_key 和 weather_api_key_file"},
+		{Field: "wecom_webhook", Message: "环境变量 MISSING_WEBHOOK 未设置"},
+	}
+	if !reflect.DeepEqual(verr.Problems, want) {
+		t.Errorf("problems = %v, want %v", verr.Problems, want)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/robfig/cron/v3"
+	"wechatrobot/internal/holiday"
+)
+
+// Problem 配置中的一个问题
+type Problem struct {
+	Field   string // 配置路径，如 "holidays[0].end_date"
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + ": " + p.Message
+}
+
+// ValidationError 配置校验失败，包含发现的所有问题
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "配置文件 %s 有 %d 个问题:", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// 和风天气的 LocationID（如 101010100）或 "经度,纬度"（如 116.41,39.92）
+var (
+	locationIDPattern  = regexp.MustCompile(`^\d{9}$`)
+	coordinatesPattern = regexp.MustCompile(`^-?\d{1,3}(\.\d{1,2})?,-?\d{1,2}(\.\d{1,2})?$`)
+)
+
+// Validate 检查配置，返回所有问题；没有问题时返回空
+func (c *Config) Validate() []Problem {
+	var problems []Problem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		add("timezone", "时区 %q 无效，应为 IANA 名称，如 Asia/Shanghai", c.Timezone)
+	}
+
+	// 每个群都有自己的 webhook/locations 时，顶层配置可以为空
+	needWebhook, needLocations := len(c.Groups) == 0, len(c.Groups) == 0
+	for _, g := range c.Groups {
+		needWebhook = needWebhook || g.Webhook == ""
+		needLocations = needLocations || len(g.Locations) == 0
+	}
+	checkWebhook := func(field, webhook string, required bool) {
+		switch {
+		case webhook == "" && required:
+			add(field, "不能为空，请填写企业微信群机器人的 webhook 地址")
+		case webhook != "" && !strings.HasPrefix(webhook, "https://") && !strings.HasPrefix(webhook, "http://"):
+			add(field, "%q 不是 http(s) 地址", webhook)
+		}
+	}
+	checkLocations := func(field string, locations []string, required bool) {
+		if len(locations) == 0 && required {
+			add(field, "不能为空，请至少配置一个城市")
+		}
+		for i, l := range locations {
+			if !locationIDPattern.MatchString(l) && !coordinatesPattern.MatchString(l) {
+				add(fmt.Sprintf("%s[%d]", field, i), "%q 不是有效的和风天气 LocationID（9 位数字，如 101010100）或经纬度（如 116.41,39.92）", l)
+			}
+		}
+	}
+	checkWebhook("wecom_webhook", c.WecomWebhook, needWebhook)
+	checkLocations("locations", c.Locations, needLocations)
+
+	if c.WeatherAPIKey == "" {
+		add("weather_api_key", "不能为空，请填写和风天气 API Key")
+	}
+
+	hasDoubao := c.DoubaoAPIKey != ""
+	if hasDoubao && c.DoubaoURL == "" {
+		add("doubao_url", "配置了 doubao_api_key 时不能为空")
+	}
+	if hasDoubao && c.DoubaoModel == "" {
+		add("doubao_model", "配置了 doubao_api_key 时不能为空")
+	}
+	if c.UseAIReminder && !hasDoubao && c.OpenAIAPIKey == "" {
+		add("use_ai_reminder", "已启用 AI 下班提醒，但 doubao_api_key 和 openai_api_key 都为空")
+	}
+	if !c.UseAIReminder && len(c.OffWorkMessages) == 0 {
+		add("off_work_messages", "未启用 AI 下班提醒时不能为空")
+	}
+	if c.AIMaxAttempts < 0 {
+		add("ai_max_attempts", "不能为负数")
+	}
+	if c.Health.Timeout < 0 {
+		add("health.timeout", "不能为负数")
+	}
+	if c.History.RetentionDays < 0 {
+		add("history.retention_days", "不能为负数")
+	}
+	if err := c.Log.Validate(); err != nil {
+		add("log", "%v", err)
+	}
+
+	for _, err := range []error{
+		holiday.ValidateHolidays(c.Holidays),
+		holiday.ValidateLeaves(c.Leave.Entries),
+		holiday.ValidateCalendarConfigs(c.Calendars),
+		holiday.ValidateSpecialDays(c.SpecialDays),
+	} {
+		var fieldErrs holiday.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			for _, fe := range fieldErrs {
+				add(fe.Field, "%s", fe.Message)
+			}
+		}
+	}
+
+	// 群引用的日历必须已定义
+	known := map[string]bool{holiday.DefaultCalendar: true}
+	for _, cal := range c.Calendars {
+		known[cal.Name] = true
+	}
+	for i, g := range c.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		if g.Name == "" {
+			add(path+".name", "缺少 name")
+		}
+		checkWebhook(path+".webhook", g.Webhook, false)
+		checkLocations(path+".locations", g.Locations, false)
+		for j, name := range g.Calendars {
+			if !known[name] {
+				add(fmt.Sprintf("%s.calendars[%d]", path, j), "未定义的日历 %s", name)
+			}
+		}
+	}
+
+	for _, s := range []struct {
+		key  string
+		spec string
+	}{
+		{"daily_report", c.Schedules.DailyReport},
+		{"off_work", c.Schedules.OffWork},
+		{"refresh_calendars", c.Schedules.RefreshCalendars},
+	} {
+		if _, err := cron.ParseStandard(s.spec); err != nil {
+			add("schedules."+s.key, "cron 表达式 %q 无效: %v", s.spec, err)
+		}
+	}
+	return problems
+}
+
+// 解析错误中第一个引号内的是字段路径，如 "error decoding 'holidays[0].start_date': 日期 ... 无效"、
+// "cannot parse 'chat_limit.workers' as int: ..."
+var decodeFieldPattern = regexp.MustCompile(`'([^']*)'`)
+
+// decodeProblems 把 mapstructure 的解析错误转换为问题列表
+func decodeProblems(err *mapstructure.Error) []Problem {
+	problems := make([]Problem, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		m := decodeFieldPattern.FindStringSubmatch(e)
+		if m == nil {
+			problems = append(problems, Problem{Message: e})
+			continue
+		}
+		message := strings.TrimPrefix(e, "error decoding '"+m[1]+"': ")
+		problems = append(problems, Problem{Field: m[1], Message: message})
+	}
+	return problems
+}
+
+// mergeProblems 合并解析和校验发现的问题，解析失败的字段不再重复报告为缺失
+func mergeProblems(decoded, validated []Problem) []Problem {
+	failed := make(map[string]bool, len(decoded))
+	for _, p := range decoded {
+		failed[p.Field] = true
+	}
+	problems := decoded
+	for _, p := range validated {
+		if !failed[p.Field] {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
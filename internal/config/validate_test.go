@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckReportsAllProblems(t *testing.T) {
+	useConfigDir(t, `
+wecom_webhook: ""
+locations: ["beijing", "101010100"]
+use_ai_reminder: true
+holidays:
+  - name: "年假"
+    start_date: "2026-02-30"
+    end_date: "2026-03-01"
+  - name: "团建"
+    start_date: "2026-03-05"
+groups:
+  - name: "香港"
+    webhook: "https://example.com/hk"
+    calendars: ["hk"]
+schedules:
+  off_work: "18:00"
+`)
+
+	_, err := Check()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Check() error = %v, want *ValidationError", err)
+	}
+	var fields []string
+	for _, p := range verr.Problems {
+		fields = append(fields, p.Field)
+	}
+	want := []string{
+		"holidays[0].start_date",
+		"locations[0]",
+		"weather_api_key",
+		"use_ai_reminder",
+		"holidays[1].end_date",
+		"groups[0].calendars[0]",
+		"schedules.off_work",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("problem fields = %v\nwant %v", fields, want)
+	}
+}
+
+func TestValidateGroupsWithOwnWebhooks(t *testing.T) {
+	cfg := &Config{
+		Timezone:        "Asia/Shanghai",
+		WeatherAPIKey:   "key",
+		OffWorkMessages: []string{"下班啦"},
+		Groups: []GroupConfig{
+			{Name: "北京", Webhook: "https://example.com/bj", Locations: []string{"101010100"}},
+			{Name: "深圳", Webhook: "https://example.com/sz", Locations: []string{"114.06,22.54"}},
+		},
+		Schedules: ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *", RefreshCalendars: "30 7 * * *"},
+	}
+	if problems := cfg.Validate(); len(problems) != 0 {
+		t.Errorf("Validate() = %v, want no problems when every group has its own webhook and locations", problems)
+	}
+}
@@ -1,65 +1,159 @@
 package cronn
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 	"wechatrobot/internal/config"
 	"wechatrobot/internal/holiday"
 	"wechatrobot/internal/ai"
+	"wechatrobot/internal/history"
 	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/usage"
 	"wechatrobot/internal/weather"
-
-	"github.com/sirupsen/logrus"
 )
 
-func SendDailyReport() {
-	logrus.Info("天气报告定时任务触发")
+// Runner 执行定时任务，时间来源、消息发送方式和配置都可替换，便于测试指定日期的行为
+type Runner struct {
+	Clock  holiday.Clock
+	Sender weather.Sender // 为空时通过各群的 webhook 发送
+	Cfg    *config.Config // 为空时使用全局配置
+	DryRun bool           // Sender 不发送到群，执行记录中标记为 dry-run
+	NoAI   bool           // 不调用 AI 生成下班提醒，用于通过 HTTP 预览
+}
+
+// NewRunner 创建使用全局配置的 Runner
+func NewRunner(clock holiday.Clock, sender weather.Sender) *Runner {
+	return &Runner{Clock: clock, Sender: sender}
+}
+
+// 定时任务默认使用系统时间，通过各群的 webhook 发送
+var defaultRunner = NewRunner(holiday.SystemClock{}, nil)
+
+func (r *Runner) cfg() *config.Config {
+	if r.Cfg != nil {
+		return r.Cfg
+	}
+	return config.Get()
+}
+
+// sender 返回发送到群的 Sender
+func (r *Runner) sender(group config.GroupConfig) weather.Sender {
+	if gs, ok := r.Sender.(weather.GroupSender); ok {
+		return gs.ForGroup(group.Name)
+	}
+	if r.Sender != nil {
+		return r.Sender
+	}
+	return weather.WecomSender{Webhook: group.Webhook}
+}
+
+// runJob 对每个群执行任务，记录结果和每个群的执行记录
+func (r *Runner) runJob(ctx context.Context, job string, run func(ctx context.Context, group config.GroupConfig, gr *groupRun) string) {
+	start := time.Now()
+	ctx = log.WithField(ctx, log.FieldJob, job)
+	trigger, scheduled := triggerOf(ctx, r.Clock.Now())
+	outcomes := make(map[string]string)
+	var runs []history.Run
+	for _, group := range r.cfg().EffectiveGroups() {
+		gr := &groupRun{sender: r.sender(group)}
+		groupStart := time.Now()
+		outcome := run(log.WithField(ctx, log.FieldGroup, group.Name), group, gr)
+		outcomes[group.Name] = outcome
+		runs = append(runs, history.Run{
+			Job: job, Group: group.Name, Trigger: trigger, Scheduled: scheduled,
+			Start: groupStart, End: time.Now(), Outcome: outcome, SkipReason: gr.reason,
+			Messages: gr.messages, Errors: gr.errors, DryRun: r.DryRun,
+		})
+	}
+	recordJob(job, start, outcomes)
+	history.Add(runs...)
+}
+
+// calendar 返回群组合使用的日历
+func calendar(ctx context.Context, group config.GroupConfig, gr *groupRun) (*holiday.Calendar, bool) {
+	cal, err := holiday.Lookup(group.Calendars)
+	if err != nil {
+		log.From(ctx).Errorf("群的日历无效: %v", err)
+		gr.addError(err)
+		return nil, false
+	}
+	return cal, true
+}
+
+// SendDailyReport 向每个群发送每日天气报告，ctx 取消时中止天气查询和发送
+func (r *Runner) SendDailyReport(ctx context.Context) {
+	log.From(ctx).Info("天气报告定时任务触发")
+	r.runJob(ctx, metrics.JobDailyReport, r.sendDailyReport)
+}
+
+// sendDailyReport 按群的日历判断并发送天气报告，返回执行结果
+func (r *Runner) sendDailyReport(ctx context.Context, group config.GroupConfig, gr *groupRun) string {
+	cfg := r.cfg()
+	now := r.Clock.Now()
+	cal, ok := calendar(ctx, group, gr)
+	if !ok {
+		return metrics.OutcomeFailed
+	}
 	
+	mentions := holiday.FilterMentions(now, group.MentionUsers, cfg.Leave.Team)
+
 	// 检查是否应该发送提醒
-	shouldSend, isFestival, festival := holiday.ShouldSendReminder(config.Cfg.Holidays)
+	shouldSend, isFestival, festival := cal.ShouldSendReminder(r.Clock, cfg.Holidays)
 	
 	if isFestival && festival != nil {
-		// 节假日：发送特色问候
-		festivalMessage := fmt.Sprintf("%s\n\n除夕至初六期间天气报告已暂停，假期结束后继续为您服务。", festival.Greeting)
-		if err := weather.SendWecomMessage(festivalMessage, config.Cfg.MentionUsers); err != nil {
-			log.Error("发送节假日问候失败: ", err)
+		// 节假日：只在第一天发送特色问候
+		if !festival.IsFirstDay(now) {
+			log.From(ctx).Infof("%s假期中，问候已在首日发送，跳过天气报告", festival.Name)
+			return gr.skip(holiday.SkipFestival)
+		}
+		if err := gr.send(ctx, festival.GreetingMessage(), mentions); err != nil {
+			log.From(ctx).Error("发送节假日问候失败: ", err)
 			weather.SendErrorAlert(err)
-			return
+			return metrics.OutcomeFailed
 		}
-		logrus.Info("节假日问候发送成功")
-		return
+		log.From(ctx).Info("节假日问候发送成功")
+		return metrics.OutcomeSuccess
 	}
 	
 	if !shouldSend {
-		logrus.Info("当前为假期或非工作日，跳过天气报告")
-		return
+		log.From(ctx).Info("当前为假期或非工作日，跳过天气报告")
+		return gr.skip(cal.SkipReason(now, cfg.Holidays))
 	}
 	
 	var fullReport string
+	reported := 0 // 获取到天气的城市数
 
 	// 遍历所有配置的城市
-	for _, location := range config.Cfg.Locations {
+	for _, location := range group.Locations {
+		ctx := log.WithField(ctx, log.FieldLocation, location)
 		// 获取实时天气
-		currentWeather, err := weather.GetWeather(location, "current")
+		currentWeather, err := weather.GetWeather(ctx, location, "current")
 		if err != nil {
-			log.Error("获取实时天气失败: ", err)
+			log.From(ctx).Error("获取实时天气失败: ", err)
+			gr.addError(err)
 			weather.SendErrorAlert(err)
 			continue
 		}
 
 		// 获取天气预报
-		forecast, err := weather.GetWeather(location, "7d")
+		forecast, err := weather.GetWeather(ctx, location, "7d")
 		if err != nil {
-			log.Error("获取天气预报失败: ", err)
+			log.From(ctx).Error("获取天气预报失败: ", err)
+			gr.addError(err)
 			weather.SendErrorAlert(err)
 			continue
 		}
 
 		// 获取生活指数
-		indices, err := weather.GetLivingIndices(location)
+		indices, err := weather.GetLivingIndices(ctx, location)
 		if err != nil {
-			log.Error("获取生活指数失败: ", err)
+			log.From(ctx).Error("获取生活指数失败: ", err)
+			gr.addError(err)
 			weather.SendErrorAlert(err)
 			continue
 		}
@@ -91,63 +185,275 @@ func SendDailyReport() {
 		}
 
 		fullReport += cityReport + "\n------------------------\n"
+		reported++
+	}
+
+	// 所有城市的天气都没有获取到时不发送空报告
+	if reported == 0 && len(group.Locations) > 0 {
+		log.From(ctx).Error("所有城市的天气都获取失败，不发送天气报告")
+		return metrics.OutcomeFailed
+	}
+
+	// 节后第一天：欢迎回来，附上假期天气回顾
+	if cfg.Festival.WelcomeBack {
+		if ended := cal.EndedFestival(now, cfg.Holidays); ended != nil {
+			fullReport = welcomeBackMessage(ctx, ended, now, group.Locations) + "\n------------------------\n" + fullReport
+		}
+	}
+
+	// 节前最后一个工作日：提醒即将放假
+	if cfg.Festival.PreHoliday {
+		if upcoming := cal.UpcomingFestival(now, cfg.Holidays); upcoming != nil {
+			fullReport += upcoming.PreHolidayMessage() + "\n\n"
+		}
+	}
+
+	// 节日倒计时、节气、生日和入职周年
+	specials := r.specialDays(ctx, cal, group, now)
+	for _, m := range specials {
+		if !m.Standalone {
+			fullReport += m.Text + "\n\n"
+		}
+	}
+
+	// 今日请假的同事
+	if cfg.Leave.ShowInReport {
+		if leaves := holiday.OnLeave(now); len(leaves) > 0 {
+			names := make([]string, len(leaves))
+			for i, l := range leaves {
+				names[i] = l.DisplayName()
+			}
+			fullReport += fmt.Sprintf("🏖 今日请假：%s\n\n", strings.Join(names, "、"))
+		}
 	}
 
 	// 添加结尾
 	fullReport += "💡 温馨提示：记得关注天气变化哦！"
 
 	// 发送消息
-	if err := weather.SendWecomMessage(fullReport, config.Cfg.MentionUsers); err != nil {
-		log.Error("发送消息失败: ", err)
+	if err := gr.send(ctx, fullReport, mentions); err != nil {
+		log.From(ctx).Error("发送消息失败: ", err)
 		weather.SendErrorAlert(err)
-		return
+		return metrics.OutcomeFailed
+	}
+
+	log.From(ctx).Info("每日天气报告发送成功")
+
+	for _, m := range specials {
+		if !m.Standalone {
+			continue
+		}
+		if err := gr.send(ctx, m.Text, m.Mentions); err != nil {
+			log.From(ctx).Errorf("发送%s消息失败: %v", m.Kind, err)
+		}
+	}
+	if reported < len(group.Locations) {
+		return metrics.OutcomePartial
+	}
+	return metrics.OutcomeSuccess
+}
+
+// specialDays 返回群今天的特殊日子消息，生日和入职周年只发给成员所在的群
+func (r *Runner) specialDays(ctx context.Context, cal *holiday.Calendar, group config.GroupConfig, now time.Time) []holiday.SpecialMessage {
+	cfg := r.cfg()
+	special := cfg.SpecialDays
+	special.People = nil
+	for _, p := range cfg.SpecialDays.People {
+		if p.InGroup(group.Name) {
+			special.People = append(special.People, p)
+		}
 	}
 
-	logrus.Info("每日天气报告发送成功")
+	messages, err := cal.SpecialDays(special, now, cfg.Holidays)
+	if err != nil {
+		log.From(ctx).Errorf("生成特殊日子消息失败: %v", err)
+		return nil
+	}
+	return messages
+}
+
+// SendOffWorkReminder 向每个群发送下班提醒，ctx 取消时中止 AI 生成和发送
+func (r *Runner) SendOffWorkReminder(ctx context.Context) {
+	r.runJob(ctx, metrics.JobOffWork, r.sendOffWorkReminder)
 }
 
-// 发送下班提醒
-func SendOffWorkReminder() {
+// sendOffWorkReminder 按群的日历判断并发送下班提醒，返回执行结果
+func (r *Runner) sendOffWorkReminder(ctx context.Context, group config.GroupConfig, gr *groupRun) string {
+	cfg := r.cfg()
+	cal, ok := calendar(ctx, group, gr)
+	if !ok {
+		return metrics.OutcomeFailed
+	}
+
 	// 检查是否应该发送下班提醒
-	shouldSend, _, _ := holiday.ShouldSendOffWorkReminder(config.Cfg.Holidays)
+	shouldSend, _, _ := cal.ShouldSendOffWorkReminder(r.Clock, cfg.Holidays)
 	
 	if !shouldSend {
-		logrus.Info("当前为假期、节假日或非工作日，跳过下班提醒")
-		return
+		log.From(ctx).Info("当前为假期、节假日或非工作日，跳过下班提醒")
+		return gr.skip(cal.SkipReason(r.Clock.Now(), cfg.Holidays))
 	}
 	
 	var content string
 
-	// 如果启用了 AI 模式，使用 AI 生成提醒
-	if config.Cfg.UseAIReminder {
-		generatedMessage, err := ai.GenerateOffWorkReminder(config.Cfg.DoubaoURL, config.Cfg.DoubaoAPIKey, config.Cfg.DoubaoModel, config.Cfg.OpenAIAPIKey)
+	// 如果启用了 AI 模式且今日预算未用完，使用 AI 生成提醒
+	useAI := cfg.UseAIReminder
+	if useAI && r.NoAI {
+		return r.sendReminder(ctx, group, gr, "提醒：（由 AI 生成，预览时不调用 AI）")
+	}
+	if useAI && usage.BudgetExceeded() {
+		log.From(ctx).Warn("今日 AI 用量已超出预算，下班提醒改用静态文案")
+		useAI = false
+	}
+
+	if useAI {
+		opts := ai.FilterOptions{
+			BannedWords:     cfg.BannedWords,
+			MaxAttempts:     cfg.AIMaxAttempts,
+			RejectedLogFile: cfg.AIRejectedLog,
+		}
+		generatedMessage, err := ai.GenerateOffWorkReminder(ctx, cfg.DoubaoURL, cfg.DoubaoAPIKey, cfg.DoubaoModel, cfg.OpenAIAPIKey, opts)
 		if err != nil {
-			logrus.Error("使用 AI 生成提醒失败: ", err)
+			log.From(ctx).Error("使用 AI 生成提醒失败: ", err)
+			gr.addError(err)
 			// 降级到静态文案
-			if len(config.Cfg.OffWorkMessages) > 0 {
-				content = fmt.Sprintf("提醒：%s", config.Cfg.OffWorkMessages[0])
-			} else {
-				logrus.Error("生成提醒失败，且没有备用文案")
-				return
+			if len(cfg.OffWorkMessages) == 0 {
+				log.From(ctx).Error("生成提醒失败，且没有备用文案")
+				gr.addError(errors.New("生成提醒失败，且没有备用文案"))
+				return metrics.OutcomeFailed
 			}
+			content = fmt.Sprintf("提醒：%s", randomOffWorkMessage(cfg.OffWorkMessages))
 		} else {
 			content = fmt.Sprintf("提醒：%s", generatedMessage)
 		}
 	} else {
 		// 使用静态文案模式
-		if len(config.Cfg.OffWorkMessages) == 0 {
-			logrus.Error("下班结束语配置为空且未启用 AI 模式")
-			return
+		if len(cfg.OffWorkMessages) == 0 {
+			log.From(ctx).Error("下班结束语配置为空且未启用 AI 模式")
+			gr.addError(errors.New("下班结束语配置为空且未启用 AI 模式"))
+			return metrics.OutcomeFailed
 		}
-		rand.Seed(time.Now().Unix())
-		message := config.Cfg.OffWorkMessages[rand.Intn(len(config.Cfg.OffWorkMessages))]
-		content = fmt.Sprintf("提醒：%s", message)
+		content = fmt.Sprintf("提醒：%s", randomOffWorkMessage(cfg.OffWorkMessages))
 	}
 
-	if err := weather.SendWecomMessage(content, config.Cfg.MentionUsers); err != nil {
-		logrus.Error("发送下班提醒失败: ", err)
-		return
+	return r.sendReminder(ctx, group, gr, content)
+}
+
+// sendReminder 发送下班提醒，@ 群成员中没有请假的人
+func (r *Runner) sendReminder(ctx context.Context, group config.GroupConfig, gr *groupRun, content string) string {
+	mentions := holiday.FilterMentions(r.Clock.Now(), group.MentionUsers, r.cfg().Leave.Team)
+	if err := gr.send(ctx, content, mentions); err != nil {
+		log.From(ctx).Errorf("发送下班提醒失败: %v", err)
+		return metrics.OutcomeFailed
 	}
 
-	logrus.Info("下班提醒发送成功")
+	log.From(ctx).Info("下班提醒发送成功")
+	return metrics.OutcomeSuccess
+}
+
+// randomOffWorkMessage 从静态文案中随机选一条
+func randomOffWorkMessage(messages []string) string {
+	rand.Seed(time.Now().UnixNano())
+	return messages[rand.Intn(len(messages))]
+}
+
+// RefreshHolidayICS 重新加载自定义日历，导入 ICS 日历中的假期和请假，范围为去年到后年
+// 启动和配置变更时调用，不写入任务执行记录
+func (r *Runner) RefreshHolidayICS() {
+	r.refresh(log.WithField(context.Background(), log.FieldJob, metrics.JobRefreshCalendars), &groupRun{})
+}
+
+// refreshCalendars 定时刷新日历，写入任务执行记录
+func (r *Runner) refreshCalendars(ctx context.Context) {
+	start := time.Now()
+	ctx = log.WithField(ctx, log.FieldJob, metrics.JobRefreshCalendars)
+	trigger, scheduled := triggerOf(ctx, r.Clock.Now())
+	gr := &groupRun{}
+	outcome := r.refresh(ctx, gr)
+	history.Add(history.Run{
+		Job: metrics.JobRefreshCalendars, Trigger: trigger, Scheduled: scheduled,
+		Start: start, End: time.Now(), Outcome: outcome, Errors: gr.errors,
+	})
+}
+
+// refresh 执行日历刷新并记录结果和指标，返回执行结果
+func (r *Runner) refresh(ctx context.Context, gr *groupRun) string {
+	cfg := r.cfg()
+	now := r.Clock.Now()
+	from := now.AddDate(-1, 0, 0)
+	to := now.AddDate(2, 0, 0)
+	start := time.Now()
+	outcome := metrics.OutcomeSuccess
+	logger := log.From(ctx)
+
+	if len(cfg.Calendars) > 0 {
+		if err := holiday.LoadCalendars(cfg.Calendars, from, to); err != nil {
+			logger.Error("加载自定义日历失败: ", err)
+			gr.addError(err)
+			weather.SendErrorAlert(err)
+			outcome = metrics.OutcomeFailed
+		} else {
+			logger.Infof("已加载自定义日历 %v", holiday.CalendarNames())
+		}
+	}
+
+	if len(cfg.HolidayICS) > 0 {
+		if err := holiday.ImportICS(cfg.HolidayICS, from, to); err != nil {
+			logger.Error("导入 ICS 假期失败: ", err)
+			gr.addError(err)
+			weather.SendErrorAlert(err)
+			outcome = metrics.OutcomeFailed
+		} else {
+			logger.Infof("已从 ICS 日历导入 %d 个假期", len(holiday.ImportedHolidays()))
+		}
+	}
+
+	if len(cfg.Leave.ICS) > 0 {
+		if err := holiday.ImportLeaveICS(cfg.Leave.ICS, from, to); err != nil {
+			logger.Error("导入请假 ICS 失败: ", err)
+			gr.addError(err)
+			weather.SendErrorAlert(err)
+			outcome = metrics.OutcomeFailed
+		} else {
+			logger.Info("已从 ICS 日历导入请假")
+		}
+	}
+	recordJob(metrics.JobRefreshCalendars, start, map[string]string{"": outcome})
+	return outcome
+}
+
+// 和风天气历史天气只能查询最近 10 天
+const historicalWeatherDays = 10
+
+// welcomeBackMessage 节后第一天的欢迎语，附上各城市的假期天气回顾
+func welcomeBackMessage(ctx context.Context, festival *holiday.Festival, now time.Time, locations []string) string {
+	message := fmt.Sprintf("🎒 节后第一天，欢迎回来！%s假期（%s）已经结束，收收心，开工大吉！\n", festival.Name, festival.Period())
+
+	start, end := festival.StartDate.Time(), festival.EndDate.Time()
+	earliest := holiday.DateOf(now).AddDays(-historicalWeatherDays).Time()
+	if start.Before(earliest) {
+		start = earliest
+	}
+
+	for _, location := range locations {
+		ctx := log.WithField(ctx, log.FieldLocation, location)
+		recap := fmt.Sprintf("\n🗓 %s假期天气回顾：\n", weather.GetCityName(location))
+		days := 0
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			historical, err := weather.GetHistoricalWeather(ctx, location, day)
+			if err != nil {
+				log.From(ctx).Warnf("获取 %s 历史天气失败: %v", day.Format("2006-01-02"), err)
+				continue
+			}
+			recap += fmt.Sprintf("【%d月%d日】%s %s℃～%s℃\n",
+				day.Month(), day.Day(),
+				historical.DominantText(),
+				historical.WeatherDaily.TempMin,
+				historical.WeatherDaily.TempMax)
+			days++
+		}
+		if days > 0 {
+			message += recap
+		}
+	}
+	return message
 }
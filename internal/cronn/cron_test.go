@@ -0,0 +1,331 @@
+package cronn
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/history"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/metrics"
+
+	"github.com/robfig/cron/v3"
+)
+
+// fakeSender 记录发送的消息
+type fakeSender struct {
+	messages []string
+	mentions [][]string
+}
+
+func (s *fakeSender) Send(ctx context.Context, content string, mentionUsers []string) error {
+	s.messages = append(s.messages, content)
+	s.mentions = append(s.mentions, mentionUsers)
+	return nil
+}
+
+func at(date string, hour int) holiday.FixedClock {
+	t, _ := time.ParseInLocation("2006-01-02", date, holiday.Location())
+	return holiday.FixedClock(t.Add(time.Duration(hour) * time.Hour))
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{
+		Holidays: []holiday.Holiday{
+			{Name: "团建", StartDate: holiday.Date{Year: 2026, Month: 3, Day: 10}, EndDate: holiday.Date{Year: 2026, Month: 3, Day: 11}},
+		},
+		OffWorkMessages: []string{"下班啦"},
+	}
+	cfg.Festival.PreHoliday = true
+	cfg.Festival.WelcomeBack = true
+	return cfg
+}
+
+func TestSendDailyReport(t *testing.T) {
+	tests := []struct {
+		name string
+		date string
+		want []string // 每条消息应包含的内容，nil 表示不发送
+	}{
+		{"Spring Festival first day", "2026-02-15", []string{"春节假期（2月15日至2月23日，共9天）"}},
+		{"During Spring Festival", "2026-02-17", nil},
+		{"Adjusted Saturday before Spring Festival", "2026-02-14", []string{"春节假期（2月15日至2月23日，共9天）即将开始"}},
+		{"First workday after Spring Festival", "2026-02-24", []string{"欢迎回来！春节假期"}},
+		{"Ordinary Monday", "2026-03-02", []string{"温馨提示"}},
+		{"Ordinary Saturday", "2026-10-17", nil},
+		{"Configured holiday", "2026-03-10", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			r := &Runner{Clock: at(tt.date, 8), Sender: sender, Cfg: testConfig()}
+			r.SendDailyReport(context.Background())
+
+			if len(sender.messages) != len(tt.want) {
+				t.Fatalf("sent %d messages, want %d: %q", len(sender.messages), len(tt.want), sender.messages)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(sender.messages[i], want) {
+					t.Errorf("message %q does not contain %q", sender.messages[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSendOffWorkReminder(t *testing.T) {
+	tests := []struct {
+		name string
+		date string
+		send bool
+	}{
+		{"Ordinary Friday", "2026-10-16", true},
+		{"Ordinary Saturday", "2026-10-17", false},
+		{"Adjusted Saturday", "2026-02-14", true},
+		{"National Day", "2026-10-02", false},
+		{"Configured holiday", "2026-03-11", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			r := &Runner{Clock: at(tt.date, 18), Sender: sender, Cfg: testConfig()}
+			r.SendOffWorkReminder(context.Background())
+
+			if sent := len(sender.messages) == 1; sent != tt.send {
+				t.Fatalf("sent = %v, want %v: %q", sent, tt.send, sender.messages)
+			}
+			if tt.send && sender.messages[0] != "提醒：下班啦" {
+				t.Errorf("message = %q", sender.messages[0])
+			}
+		})
+	}
+}
+
+func TestMentionsSkipPeopleOnLeave(t *testing.T) {
+	err := holiday.InitLeaves(holiday.LeaveConfig{Entries: []holiday.Leave{
+		{User: "alice", Name: "小艾", StartDate: holiday.Date{Year: 2026, Month: 10, Day: 19}, EndDate: holiday.Date{Year: 2026, Month: 10, Day: 23}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holiday.InitLeaves(holiday.LeaveConfig{})
+
+	cfg := testConfig()
+	cfg.MentionUsers = []string{"@all"}
+	cfg.Leave.Team = []string{"alice", "bob"}
+	cfg.Leave.ShowInReport = true
+
+	sender := &fakeSender{}
+	r := &Runner{Clock: at("2026-10-19", 8), Sender: sender, Cfg: cfg}
+	r.SendDailyReport(context.Background())
+	r.Clock = at("2026-10-19", 18)
+	r.SendOffWorkReminder(context.Background())
+
+	if len(sender.messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(sender.messages))
+	}
+	if !strings.Contains(sender.messages[0], "今日请假：小艾") {
+		t.Errorf("report = %q", sender.messages[0])
+	}
+	for i, mentions := range sender.mentions {
+		if !reflect.DeepEqual(mentions, []string{"bob"}) {
+			t.Errorf("message %d mentions = %v, want [bob]", i, mentions)
+		}
+	}
+}
+
+func TestGroupsUseTheirOwnCalendars(t *testing.T) {
+	cals := []holiday.CalendarConfig{{
+		Name:      "hk",
+		Festivals: []holiday.Festival{{Name: "國慶日", StartDate: holiday.Date{Year: 2026, Month: 10, Day: 1}, EndDate: holiday.Date{Year: 2026, Month: 10, Day: 1}, Greeting: "國慶日快樂！"}},
+	}}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, holiday.Location())
+	if err := holiday.LoadCalendars(cals, from, from.AddDate(1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	defer holiday.LoadCalendars(nil, from, from)
+
+	cfg := testConfig()
+	cfg.Calendars = cals
+	cfg.Groups = []config.GroupConfig{
+		{Name: "beijing", MentionUsers: []string{"bj"}},
+		{Name: "hongkong", Calendars: []string{"hk"}, MentionUsers: []string{"hk"}},
+	}
+
+	tests := []struct {
+		date     string
+		mentions [][]string
+		want     []string
+	}{
+		// 两地都放假，各自在首日发送问候
+		{"2026-10-01", [][]string{{"bj"}, {"hk"}}, []string{"国庆节快乐", "國慶日快樂"}},
+		// 大陆国庆假期中，香港照常上班
+		{"2026-10-02", [][]string{{"hk"}}, []string{"温馨提示"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			sender := &fakeSender{}
+			r := &Runner{Clock: at(tt.date, 8), Sender: sender, Cfg: cfg}
+			r.SendDailyReport(context.Background())
+
+			if !reflect.DeepEqual(sender.mentions, tt.mentions) {
+				t.Fatalf("mentions = %v, want %v", sender.mentions, tt.mentions)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(sender.messages[i], want) {
+					t.Errorf("message %q does not contain %q", sender.messages[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecialDayMessages(t *testing.T) {
+	cfg := testConfig()
+	cfg.SpecialDays = holiday.SpecialDaysConfig{
+		Countdown: holiday.CountdownRule{SpecialDayRule: holiday.SpecialDayRule{Enabled: true}, MaxDays: 7},
+		Birthday:  holiday.SpecialDayRule{Enabled: true, Standalone: true},
+		People: []holiday.Person{
+			{User: "alice", Name: "小艾", Birthday: "09-28"},
+			{User: "bob", Name: "阿宝", Birthday: "09-28", Groups: []string{"hongkong"}},
+		},
+	}
+
+	sender := &fakeSender{}
+	r := &Runner{Clock: at("2026-09-28", 8), Sender: sender, Cfg: cfg}
+	r.SendDailyReport(context.Background())
+
+	if len(sender.messages) != 2 {
+		t.Fatalf("sent %d messages, want 2: %q", len(sender.messages), sender.messages)
+	}
+	if !strings.Contains(sender.messages[0], "距离国庆节还有 3 天") {
+		t.Errorf("report = %q", sender.messages[0])
+	}
+	if !strings.Contains(sender.messages[1], "小艾的生日") || !reflect.DeepEqual(sender.mentions[1], []string{"alice"}) {
+		t.Errorf("birthday message = %q, mentions %v", sender.messages[1], sender.mentions[1])
+	}
+}
+
+func TestSchedulerKeepsJobsOnInvalidSchedule(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *", RefreshCalendars: "30 7 * * *"}
+	s := NewScheduler(&Runner{Clock: at("2026-10-19", 8), Sender: &fakeSender{}, Cfg: cfg})
+	if err := s.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	defer s.cron.Stop()
+	running := s.cron
+
+	invalid := *cfg
+	invalid.Schedules.OffWork = "every evening"
+	if err := s.Apply(&invalid); err == nil {
+		t.Fatal("Apply with invalid schedule should fail")
+	}
+	if s.cron != running || len(s.cron.Entries()) != 2 {
+		t.Errorf("scheduler replaced jobs after failed Apply: %d entries", len(s.cron.Entries()))
+	}
+}
+
+func TestSchedulerStopCancelsRunningJobs(t *testing.T) {
+	s := NewScheduler(&Runner{Clock: at("2026-10-19", 8), Sender: &fakeSender{}, Cfg: testConfig()})
+	started := make(chan struct{}, 1)
+	c := cron.New(cron.WithSeconds())
+	c.AddFunc("* * * * * *", func() {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-s.ctx.Done()
+	})
+	s.cron = c
+	c.Start()
+
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not start")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Stop = %v, want deadline exceeded", err)
+	}
+	if s.ctx.Err() == nil || s.Running() {
+		t.Error("running job was not cancelled")
+	}
+}
+
+func TestSchedulerApplyAfterStop(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *"}
+	s := NewScheduler(&Runner{Clock: at("2026-10-19", 8), Sender: &fakeSender{}, Cfg: cfg})
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 退出过程中热加载的配置不应重新启动定时任务
+	if err := s.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if s.Running() {
+		t.Error("Apply restarted cron after Stop")
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	defer history.Init(history.Config{})
+	if err := history.Init(history.Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.Groups = []config.GroupConfig{{Name: "history-test"}}
+	sender := &fakeSender{}
+	r := &Runner{Clock: at("2026-10-17", 18), Sender: sender, Cfg: cfg}
+	r.SendOffWorkReminder(context.Background())
+	r.Clock = at("2026-10-16", 18)
+	r.SendOffWorkReminder(withScheduled(context.Background(), time.Date(2026, 10, 16, 18, 0, 0, 0, holiday.Location())))
+
+	runs := history.Query(history.Filter{Job: metrics.JobOffWork, Group: "history-test"})
+	if len(runs) != 2 {
+		t.Fatalf("runs = %+v", runs)
+	}
+	sent, skipped := runs[0], runs[1]
+	if sent.Outcome != "success" || sent.Messages != 1 || sent.Trigger != history.TriggerSchedule || sent.Scheduled.Hour() != 18 {
+		t.Errorf("sent run = %+v", sent)
+	}
+	if skipped.Outcome != "skipped" || skipped.SkipReason != holiday.SkipWeekend || skipped.Trigger != history.TriggerManual || skipped.Messages != 0 {
+		t.Errorf("skipped run = %+v", skipped)
+	}
+}
+
+func TestDailyReportWeatherFailures(t *testing.T) {
+	cfg := testConfig()
+	cfg.Groups = []config.GroupConfig{{Name: "weather-failed", Locations: []string{"101200101", "101200805"}}}
+	sender := &fakeSender{}
+	r := &Runner{Clock: at("2026-03-02", 8), Sender: sender, Cfg: cfg}
+
+	// 请求已取消，所有城市的天气都获取失败，不发送空报告
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.SendDailyReport(ctx)
+	if len(sender.messages) != 0 {
+		t.Errorf("sent %q, want nothing", sender.messages)
+	}
+	if result, ok := LastResult(metrics.JobDailyReport); !ok || result.Outcome != "failed" || result.Groups["weather-failed"] != "failed" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 部分城市失败的群让任务整体为 partial，有群失败时为 failed
+	recordJob("partial_test", time.Now(), map[string]string{"a": "success", "b": "partial", "c": "skipped"})
+	if result, _ := LastResult("partial_test"); result.Outcome != "partial" {
+		t.Errorf("outcome = %q, want partial", result.Outcome)
+	}
+	recordJob("partial_test", time.Now(), map[string]string{"a": "failed", "b": "partial"})
+	if result, _ := LastResult("partial_test"); result.Outcome != "failed" {
+		t.Errorf("outcome = %q, want failed", result.Outcome)
+	}
+}
@@ -0,0 +1,192 @@
+package cronn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/weather"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// 可以预览的任务
+const (
+	JobReport  = "report"
+	JobOffWork = "offwork"
+)
+
+// Message 一条渲染出的消息
+type Message struct {
+	Content  string   `json:"content"`
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// recorder 记录消息而不发送
+type recorder struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (r *recorder) Send(ctx context.Context, content string, mentionUsers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, Message{Content: content, Mentions: mentionUsers})
+	return nil
+}
+
+// Preview 某个群在某一时刻执行任务会发送的消息
+type Preview struct {
+	Job      string    `json:"job"`
+	Group    string    `json:"group"`
+	Time     string    `json:"time"`
+	Messages []Message `json:"messages"` // 为空表示这一天不会发送
+}
+
+// PreviewJob 渲染群 group 在 now 时刻执行任务 job 的消息，不发送
+// group 为空时使用第一个群；天气数据为查询时的实时数据
+func PreviewJob(ctx context.Context, job, group string, now time.Time) (*Preview, error) {
+	return previewJob(ctx, &Runner{Clock: holiday.FixedClock(now), Cfg: config.Get()}, job, group)
+}
+
+// previewJob 用 r 渲染消息，r.Sender 替换为记录消息的 recorder
+func previewJob(ctx context.Context, r *Runner, job, group string) (*Preview, error) {
+	cfg := r.cfg()
+	now := r.Clock.Now()
+	g, err := FindGroup(cfg, group)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &recorder{}
+	r.Sender = rec
+	gr := &groupRun{sender: rec}
+	switch job {
+	case JobReport:
+		r.sendDailyReport(ctx, g, gr)
+	case JobOffWork:
+		r.sendOffWorkReminder(ctx, g, gr)
+	default:
+		return nil, fmt.Errorf("未知任务 %q，应为 %s 或 %s", job, JobReport, JobOffWork)
+	}
+	return &Preview{Job: job, Group: g.Name, Time: now.Format("2006-01-02 15:04"), Messages: rec.messages}, nil
+}
+
+// FindGroup 按名称查找群，name 为空时返回第一个群
+func FindGroup(cfg *config.Config, name string) (config.GroupConfig, error) {
+	groups := cfg.EffectiveGroups()
+	if name == "" {
+		return groups[0], nil
+	}
+	for _, g := range groups {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return config.GroupConfig{}, fmt.Errorf("未配置群 %q", name)
+}
+
+// ScheduledTime 返回任务在 date 这一天按配置的执行时间，当天不执行时返回 date 本身
+func ScheduledTime(cfg *config.Config, job string, date time.Time) time.Time {
+	spec := cfg.Schedules.DailyReport
+	if job == JobOffWork {
+		spec = cfg.Schedules.OffWork
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, holiday.Location())
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return date
+	}
+	next := schedule.Next(day.Add(-time.Nanosecond))
+	if next.Year() != day.Year() || next.YearDay() != day.YearDay() {
+		return date
+	}
+	return next
+}
+
+// WriteText 以文本输出预览，便于直接查看消息格式
+func (p *Preview) WriteText(w io.Writer) error {
+	var b strings.Builder
+	names := map[string]string{JobReport: "天气报告", JobOffWork: "下班提醒"}
+	fmt.Fprintf(&b, "===== %s [%s] %s =====\n", names[p.Job], p.Group, p.Time)
+	if len(p.Messages) == 0 {
+		b.WriteString("（这一天不发送）\n")
+	}
+	for i, m := range p.Messages {
+		if i > 0 {
+			b.WriteString("-----\n")
+		}
+		if len(m.Mentions) > 0 {
+			b.WriteString("提醒成员: " + strings.Join(m.Mentions, ", ") + "\n")
+		}
+		b.WriteString(m.Content + "\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HandlePreview 返回某天某个群的天气报告和下班提醒，不发送
+// 参数：date 为 YYYY-MM-DD（按配置的执行时间）或 "YYYY-MM-DD HH:MM"，默认今天；
+// group 默认第一个群；job 为 report 或 offwork，默认两个都返回；format=text 时返回纯文本
+// 天气数据优先使用 10 分钟内查询过的结果，下班提醒不调用 AI，避免消耗接口额度和 AI 预算
+func HandlePreview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	jobs := []string{JobReport, JobOffWork}
+	switch job := q.Get("job"); job {
+	case "":
+	case JobReport, JobOffWork:
+		jobs = []string{job}
+	default:
+		http.Error(w, "invalid job, want report or offwork", http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now().In(holiday.Location())
+	dateOnly := true
+	if s := q.Get("date"); s != "" {
+		clock, err := holiday.ParseNow(s)
+		if err != nil {
+			http.Error(w, "invalid date, want YYYY-MM-DD or \"YYYY-MM-DD HH:MM\"", http.StatusBadRequest)
+			return
+		}
+		date, dateOnly = clock.Now(), !strings.Contains(s, " ")
+	}
+
+	previews := make([]*Preview, 0, len(jobs))
+	for _, job := range jobs {
+		now := date
+		if dateOnly {
+			now = ScheduledTime(config.Get(), job, date)
+		}
+		runner := &Runner{Clock: holiday.FixedClock(now), Cfg: config.Get(), NoAI: true}
+		p, err := previewJob(weather.WithCache(r.Context()), runner, job, q.Get("group"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		previews = append(previews, p)
+	}
+
+	if q.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, p := range previews {
+			if err := p.WriteText(w); err != nil {
+				logrus.Errorf("输出消息预览失败: %v", err)
+				return
+			}
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(previews); err != nil {
+		logrus.Errorf("输出消息预览失败: %v", err)
+	}
+}
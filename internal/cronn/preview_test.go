@@ -0,0 +1,93 @@
+package cronn
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wechatrobot/internal/config"
+)
+
+func TestScheduledTime(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * 1-5", OffWork: "30 17 * * *"}
+
+	if got := ScheduledTime(cfg, JobOffWork, at("2026-10-19", 0).Now()); got != at("2026-10-19", 0).Now().Add(17*time.Hour+30*time.Minute) {
+		t.Errorf("offwork scheduled at %v", got)
+	}
+	// 周六不执行天气报告，保持原时间
+	if got := ScheduledTime(cfg, JobReport, at("2026-10-17", 0).Now()); got != at("2026-10-17", 0).Now() {
+		t.Errorf("report on Saturday scheduled at %v", got)
+	}
+}
+
+func TestHandlePreview(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *"}
+	cfg.Groups = []config.GroupConfig{{Name: "研发群", MentionUsers: []string{"@all"}}}
+	config.Set(cfg)
+
+	tests := []struct {
+		query    string
+		status   int
+		messages int
+	}{
+		{"job=offwork&date=2026-10-19&group=研发群", http.StatusOK, 1},
+		{"job=offwork&date=2026-10-17", http.StatusOK, 0}, // 周六
+		{"job=offwork&group=测试群", http.StatusNotFound, 0},
+		{"job=lunch", http.StatusBadRequest, 0},
+		{"date=10-19", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		HandlePreview(w, httptest.NewRequest(http.MethodGet, "/preview?"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.query, w.Code, tt.status)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var previews []Preview
+		if err := json.Unmarshal(w.Body.Bytes(), &previews); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if len(previews) != 1 || len(previews[0].Messages) != tt.messages {
+			t.Errorf("%s: previews = %+v", tt.query, previews)
+			continue
+		}
+		if tt.messages > 0 && (previews[0].Group != "研发群" || previews[0].Time != "2026-10-19 18:00" || previews[0].Messages[0].Content != "提醒：下班啦") {
+			t.Errorf("%s: preview = %+v", tt.query, previews[0])
+		}
+	}
+}
+
+func TestHandlePreviewDoesNotCallAI(t *testing.T) {
+	calls := 0
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "unexpected call", http.StatusInternalServerError)
+	}))
+	defer llm.Close()
+
+	cfg := testConfig()
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *"}
+	cfg.UseAIReminder = true
+	cfg.DoubaoURL, cfg.DoubaoAPIKey, cfg.DoubaoModel = llm.URL, "test-key", "test-model"
+	config.Set(cfg)
+
+	w := httptest.NewRecorder()
+	HandlePreview(w, httptest.NewRequest(http.MethodGet, "/preview?job=offwork&date=2026-10-19", nil))
+	var previews []Preview
+	if err := json.Unmarshal(w.Body.Bytes(), &previews); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("preview called the LLM %d times", calls)
+	}
+	if len(previews) != 1 || len(previews[0].Messages) != 1 || !strings.Contains(previews[0].Messages[0].Content, "预览时不调用 AI") {
+		t.Errorf("previews = %+v", previews)
+	}
+}
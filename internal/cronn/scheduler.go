@@ -0,0 +1,167 @@
+package cronn
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/weather"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Scheduler 按配置注册定时任务，配置变更时整体替换为新的 cron 实例
+type Scheduler struct {
+	runner  *Runner
+	mu      sync.Mutex
+	cron    *cron.Cron
+	entries map[cron.EntryID]job
+	// 被替换的 cron 实例中仍在执行的任务，Stop 时一并等待
+	replaced []context.Context
+	stopped  bool // 已调用 Stop，Apply 不再启动新的 cron 实例
+
+	// 任务执行时使用的 context，Stop 超时时取消，中止任务中的请求
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewScheduler 创建定时任务调度器，任务由 runner 执行；runner 为空时使用系统时间并通过各群 webhook 发送
+func NewScheduler(runner *Runner) *Scheduler {
+	if runner == nil {
+		runner = defaultRunner
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{runner: runner, ctx: ctx, cancel: cancel}
+}
+
+// job 一个定时任务
+type job struct {
+	key  string
+	name string
+	spec string
+	run  func(ctx context.Context)
+}
+
+// newCron 按配置创建注册好所有任务的 cron 实例
+func (s *Scheduler) newCron(cfg *config.Config) (*cron.Cron, map[cron.EntryID]job, error) {
+	c := cron.New(cron.WithLocation(holiday.Location()))
+	jobs := []job{
+		{metrics.JobDailyReport, "天气报告", cfg.Schedules.DailyReport, s.runner.SendDailyReport},
+		{metrics.JobOffWork, "下班提醒", cfg.Schedules.OffWork, s.runner.SendOffWorkReminder},
+	}
+	// 每天早上天气报告前刷新自定义日历、ICS 假期和请假
+	if len(cfg.HolidayICS) > 0 || len(cfg.Leave.ICS) > 0 || len(cfg.Calendars) > 0 {
+		jobs = append(jobs, job{metrics.JobRefreshCalendars, "刷新日历", cfg.Schedules.RefreshCalendars, s.runner.refreshCalendars})
+	}
+
+	entries := make(map[cron.EntryID]job, len(jobs))
+	for _, j := range jobs {
+		run := j.run
+		// cron 按分钟调度，触发时间取整到分钟即为计划执行时间
+		id, err := c.AddFunc(j.spec, func() { run(withScheduled(s.ctx, time.Now().Truncate(time.Minute))) })
+		if err != nil {
+			return nil, nil, fmt.Errorf("创建%s定时任务失败: %w", j.name, err)
+		}
+		entries[id] = j
+		logrus.Infof("%s定时任务已添加（%s）", j.name, j.spec)
+	}
+	return c, entries, nil
+}
+
+// Apply 按配置重新注册所有定时任务并启动，Stop 之后调用时不做任何事
+// 新任务全部注册成功后才停止旧的 cron 实例，失败时保留原来的任务
+func (s *Scheduler) Apply(cfg *config.Config) error {
+	c, entries, err := s.newCron(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil
+	}
+	pending := s.replaced[:0]
+	for _, done := range s.replaced {
+		if done.Err() == nil {
+			pending = append(pending, done)
+		}
+	}
+	s.replaced = pending
+	if s.cron != nil {
+		// 不等待正在执行的任务，它们会在旧实例中执行完
+		s.replaced = append(s.replaced, s.cron.Stop())
+	}
+	s.cron, s.entries = c, entries
+	c.Start()
+	return nil
+}
+
+// Stop 停止调度，等待正在执行的任务完成
+// ctx 到期时取消任务使用的 context（中止天气查询、AI 生成和消息发送）并返回 ctx 的错误
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	waits := s.replaced
+	if s.cron != nil {
+		waits = append(waits, s.cron.Stop())
+		s.cron, s.entries = nil, nil
+	}
+	s.replaced = nil
+	s.mu.Unlock()
+
+	for _, done := range waits {
+		select {
+		case <-done.Done():
+		case <-ctx.Done():
+			s.cancel()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Reload 应用热加载的配置：重新加载请假、节假日数据和日历，重新注册定时任务
+func (s *Scheduler) Reload(old, cfg *config.Config) {
+	changed := make(map[string]bool)
+	for _, key := range config.Diff(old, cfg) {
+		changed[key] = true
+	}
+
+	if changed["leave"] {
+		if err := holiday.InitLeaves(cfg.Leave); err != nil {
+			logrus.Error("重新加载请假登记失败: ", err)
+			weather.SendErrorAlert(err)
+		}
+	}
+	if changed["holiday_data"] {
+		if err := holiday.LoadCalendar(cfg.HolidayData, time.Now()); err != nil {
+			logrus.Error("重新加载节假日数据失败: ", err)
+			weather.SendErrorAlert(err)
+		}
+	}
+	if changed["calendars"] || changed["holiday_ics"] || changed["leave"] {
+		// RefreshHolidayICS 跳过未配置的来源，配置被删除时先清空
+		now := time.Now()
+		if len(cfg.Calendars) == 0 {
+			holiday.LoadCalendars(nil, now, now)
+		}
+		if len(cfg.HolidayICS) == 0 {
+			holiday.ImportICS(nil, now, now)
+		}
+		if len(cfg.Leave.ICS) == 0 {
+			holiday.ImportLeaveICS(nil, now, now)
+		}
+		s.runner.RefreshHolidayICS()
+	}
+	if changed["schedules"] || changed["calendars"] || changed["holiday_ics"] || changed["leave"] {
+		if err := s.Apply(cfg); err != nil {
+			logrus.Error("重新注册定时任务失败，继续使用原来的任务: ", err)
+			weather.SendErrorAlert(err)
+		}
+	}
+}
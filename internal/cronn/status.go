@@ -0,0 +1,147 @@
+package cronn
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"wechatrobot/internal/history"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/weather"
+)
+
+// groupRun 记录任务在一个群的执行情况，执行完写入任务执行记录
+type groupRun struct {
+	sender   weather.Sender
+	reason   string // 跳过的原因
+	messages int    // 发送成功的消息数
+	errors   []string
+}
+
+// send 发送消息并计数，失败时记录错误
+func (g *groupRun) send(ctx context.Context, content string, mentions []string) error {
+	if err := g.sender.Send(ctx, content, mentions); err != nil {
+		g.addError(err)
+		return err
+	}
+	g.messages++
+	return nil
+}
+
+// addError 记录执行中的错误，隐藏其中的密钥
+func (g *groupRun) addError(err error) {
+	g.errors = append(g.errors, log.Redact(err.Error()))
+}
+
+// skip 记录跳过的原因并返回 skipped
+func (g *groupRun) skip(reason string) string {
+	g.reason = reason
+	return metrics.OutcomeSkipped
+}
+
+type scheduledKey struct{}
+
+// withScheduled 标记任务由定时任务触发，at 为计划执行时间
+func withScheduled(ctx context.Context, at time.Time) context.Context {
+	return context.WithValue(ctx, scheduledKey{}, at)
+}
+
+// triggerOf 返回任务的触发方式和计划执行时间，手动执行时计划时间为 now
+func triggerOf(ctx context.Context, now time.Time) (string, time.Time) {
+	if at, ok := ctx.Value(scheduledKey{}).(time.Time); ok {
+		return history.TriggerSchedule, at
+	}
+	return history.TriggerManual, now
+}
+
+// JobResult 任务最近一次执行的结果
+type JobResult struct {
+	Start   time.Time         `json:"start"`
+	End     time.Time         `json:"end"`
+	Outcome string            `json:"outcome"`          // 按 failed、partial、success、skipped 的顺序取各群中最差的结果
+	Groups  map[string]string `json:"groups,omitempty"` // 每个群的结果
+}
+
+// outcomeRank 汇总各群结果时的优先级，越大越差
+var outcomeRank = map[string]int{
+	metrics.OutcomeSkipped: 0,
+	metrics.OutcomeSuccess: 1,
+	metrics.OutcomePartial: 2,
+	metrics.OutcomeFailed:  3,
+}
+
+var (
+	resultsMu   sync.Mutex
+	lastResults = make(map[string]JobResult)
+)
+
+// recordJob 记录任务执行结果和指标，outcomes 为每个群的结果，不区分群的任务用空字符串作键
+func recordJob(job string, start time.Time, outcomes map[string]string) {
+	result := JobResult{Start: start, End: time.Now(), Outcome: metrics.OutcomeSkipped}
+	values := make([]string, 0, len(outcomes))
+	for group, o := range outcomes {
+		values = append(values, o)
+		if group != "" {
+			if result.Groups == nil {
+				result.Groups = make(map[string]string, len(outcomes))
+			}
+			result.Groups[group] = o
+		}
+		if outcomeRank[o] > outcomeRank[result.Outcome] {
+			result.Outcome = o
+		}
+	}
+	sort.Strings(values)
+
+	resultsMu.Lock()
+	lastResults[job] = result
+	resultsMu.Unlock()
+	metrics.ObserveJob(job, start, values...)
+}
+
+// LastResult 返回任务最近一次执行的结果，尚未执行时 ok 为 false
+func LastResult(job string) (result JobResult, ok bool) {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	result, ok = lastResults[job]
+	return result, ok
+}
+
+// JobStatus 已注册的定时任务
+type JobStatus struct {
+	Job     string     `json:"job"`  // 任务名，与 schedules 中的配置项一致
+	Name    string     `json:"name"` // 中文名称
+	Spec    string     `json:"spec"`
+	Next    time.Time  `json:"next"`
+	LastRun *JobResult `json:"last_run,omitempty"`
+}
+
+// Running 定时任务是否在运行
+func (s *Scheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cron != nil
+}
+
+// Jobs 返回已注册的定时任务、下次执行时间和最近一次执行结果
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cron == nil {
+		return nil
+	}
+	var jobs []JobStatus
+	for _, e := range s.cron.Entries() {
+		j, ok := s.entries[e.ID]
+		if !ok {
+			continue
+		}
+		status := JobStatus{Job: j.key, Name: j.name, Spec: j.spec, Next: e.Next}
+		if result, ok := LastResult(j.key); ok {
+			status.LastRun = &result
+		}
+		jobs = append(jobs, status)
+	}
+	return jobs
+}
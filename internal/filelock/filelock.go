@@ -0,0 +1,32 @@
+//go:build !windows
+
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Lock 给记录文件加排他锁（锁文件为 file.lock），返回解锁函数
+// serve 和 weatherrobot send 等命令可能同时写入同一个记录文件
+func Lock(file string) (func(), error) {
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
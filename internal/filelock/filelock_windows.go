@@ -0,0 +1,6 @@
+package filelock
+
+// Lock 在 Windows 上不加锁，不要让多个进程同时写入同一个记录文件
+func Lock(file string) (func(), error) {
+	return func() {}, nil
+}
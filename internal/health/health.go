@@ -0,0 +1,199 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/weather"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckResult 一项依赖检查的结果
+type CheckResult struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Report /healthz 和 /readyz 的响应
+type Report struct {
+	Status    string        `json:"status"`
+	Problems  []string      `json:"problems,omitempty"`
+	Cron      CronStatus    `json:"cron"`
+	Checks    []CheckResult `json:"checks,omitempty"`
+	CheckedAt *time.Time    `json:"checked_at,omitempty"` // 依赖检查的时间，结果可能来自缓存
+}
+
+// CronStatus 定时任务状态
+type CronStatus struct {
+	Running bool              `json:"running"`
+	Jobs    []cronn.JobStatus `json:"jobs"`
+}
+
+// probe 一项依赖检查
+type probe struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Checker 检查定时任务和依赖的状态
+type Checker struct {
+	scheduler *cronn.Scheduler
+	probes    func(cfg *config.Config) []probe // 测试时替换
+
+	mu        sync.Mutex
+	checks    []CheckResult
+	checkedAt time.Time
+}
+
+// NewChecker 创建检查 scheduler 的 Checker
+func NewChecker(scheduler *cronn.Scheduler) *Checker {
+	return &Checker{scheduler: scheduler, probes: defaultProbes}
+}
+
+// HandleHealthz 存活检查：定时任务停止时返回 503，编排系统据此重启实例
+func (c *Checker) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := Report{Status: StatusOK, Cron: c.cronStatus()}
+	if !report.Cron.Running {
+		report.Status = StatusFail
+		report.Problems = append(report.Problems, "定时任务未运行")
+	}
+	writeReport(w, report)
+}
+
+// HandleReadyz 就绪检查：定时任务在运行且已注册任务；配置了 health.active_checks 或请求带 ?active=1 时
+// 同时检查和风天气、企业微信和 LLM 的连通性，任一不可用时返回 503
+func (c *Checker) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	report := Report{Status: StatusOK, Cron: c.cronStatus()}
+	if !report.Cron.Running {
+		report.Problems = append(report.Problems, "定时任务未运行")
+	} else if len(report.Cron.Jobs) == 0 {
+		report.Problems = append(report.Problems, "没有注册定时任务")
+	}
+
+	if cfg.Health.ActiveChecks || r.URL.Query().Get("active") == "1" {
+		checks, at := c.check(r.Context(), cfg)
+		report.Checks, report.CheckedAt = checks, &at
+		for _, check := range checks {
+			if !check.OK {
+				report.Problems = append(report.Problems, fmt.Sprintf("%s 不可用: %s", check.Name, check.Error))
+			}
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		report.Status = StatusFail
+	}
+	writeReport(w, report)
+}
+
+func (c *Checker) cronStatus() CronStatus {
+	if c.scheduler == nil {
+		return CronStatus{}
+	}
+	return CronStatus{Running: c.scheduler.Running(), Jobs: c.scheduler.Jobs()}
+}
+
+// check 执行依赖检查，health.cache_ttl 内重复请求返回缓存的结果
+func (c *Checker) check(ctx context.Context, cfg *config.Config) ([]CheckResult, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < cfg.Health.CacheTTL {
+		return c.checks, c.checkedAt
+	}
+
+	timeout := cfg.Health.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	probes := c.probes(cfg)
+	results := make([]CheckResult, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p probe) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := p.check(ctx)
+			results[i] = CheckResult{Name: p.name, OK: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				// 错误中可能包含带 key 的 URL
+				results[i].Error = log.Redact(err.Error())
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	c.checks, c.checkedAt = results, time.Now()
+	return c.checks, c.checkedAt
+}
+
+// defaultProbes 按配置检查和风天气、各群 webhook 所在的企业微信服务和已配置的 LLM
+func defaultProbes(cfg *config.Config) []probe {
+	probes := []probe{{"qweather", weather.Ping}}
+
+	hosts := make(map[string]bool)
+	for _, g := range cfg.EffectiveGroups() {
+		u, err := url.Parse(g.Webhook)
+		if err != nil || u.Host == "" || hosts[u.Host] {
+			continue
+		}
+		hosts[u.Host] = true
+		probes = append(probes, probe{"wecom", reachable(u.Scheme + "://" + u.Host + "/")})
+	}
+
+	if cfg.DoubaoAPIKey != "" && cfg.DoubaoURL != "" {
+		probes = append(probes, probe{"llm:Doubao", reachable(cfg.DoubaoURL)})
+	}
+	if cfg.OpenAIAPIKey != "" {
+		probes = append(probes, probe{"llm:OpenAI", reachable("https://api.openai.com/v1/models")})
+	}
+	return probes
+}
+
+// reachable 检查地址能否连通，收到 5xx 以外的 HTTP 响应即算可用（不发送消息、不消耗额度）
+func reachable(target string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("状态码 %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logrus.Errorf("输出健康检查结果失败: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/holiday"
+)
+
+func get(t *testing.T, handler http.HandlerFunc, target string) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var report Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: %v", target, err)
+	}
+	return w.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+	c := NewChecker(cronn.NewScheduler(nil))
+	if code, report := get(t, c.HandleHealthz, "/healthz"); code != http.StatusServiceUnavailable || report.Cron.Running {
+		t.Errorf("healthz before scheduler started = %d %+v", code, report)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	cfg := &config.Config{OffWorkMessages: []string{"下班啦"}}
+	cfg.Schedules = config.ScheduleConfig{DailyReport: "0 8 * * *", OffWork: "0 18 * * *"}
+	cfg.Health.CacheTTL = time.Minute
+	config.Set(cfg)
+
+	scheduler := cronn.NewScheduler(cronn.NewRunner(holiday.SystemClock{}, nil))
+	if err := scheduler.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+	c := NewChecker(scheduler)
+	calls := 0
+	weatherErr := errors.New("dial tcp: i/o timeout")
+	c.probes = func(*config.Config) []probe {
+		return []probe{
+			{"qweather", func(context.Context) error { calls++; return weatherErr }},
+			{"wecom", func(context.Context) error { return nil }},
+		}
+	}
+
+	code, report := get(t, c.HandleReadyz, "/readyz")
+	if code != http.StatusOK || report.Status != StatusOK || len(report.Checks) != 0 {
+		t.Errorf("readyz = %d %+v", code, report)
+	}
+	if len(report.Cron.Jobs) != 2 || report.Cron.Jobs[0].Next.IsZero() {
+		t.Errorf("jobs = %+v", report.Cron.Jobs)
+	}
+
+	code, report = get(t, c.HandleReadyz, "/readyz?active=1")
+	if code != http.StatusServiceUnavailable || len(report.Checks) != 2 || report.Checks[0].OK || !report.Checks[1].OK {
+		t.Errorf("readyz?active=1 = %d %+v", code, report)
+	}
+
+	// 缓存时间内不重复检查
+	get(t, c.HandleReadyz, "/readyz?active=1")
+	if calls != 1 {
+		t.Errorf("probe called %d times, want 1", calls)
+	}
+}
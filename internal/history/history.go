@@ -0,0 +1,334 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"wechatrobot/internal/filelock"
+	"wechatrobot/internal/holiday"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config 定时任务执行记录配置
+type Config struct {
+	File          string `mapstructure:"file"`           // 记录文件（JSON Lines），为空则只保存在内存中
+	RetentionDays int    `mapstructure:"retention_days"` // 记录保留天数，0 表示一直保留
+}
+
+// 触发方式
+const (
+	TriggerSchedule = "schedule" // 按 schedules 定时执行
+	TriggerManual   = "manual"   // 命令行手动执行，或启动、配置变更时执行
+)
+
+// Run 任务在一个群的一次执行
+type Run struct {
+	Job        string    `json:"job"`             // 任务名，与 schedules 中的配置项一致
+	Group      string    `json:"group,omitempty"` // 不区分群的任务为空
+	Trigger    string    `json:"trigger"`
+	Scheduled  time.Time `json:"scheduled"` // 计划执行时间，手动执行时为执行的日期时间（含 --now 模拟的时间）
+	Start      time.Time `json:"start"`     // 实际开始时间
+	End        time.Time `json:"end"`
+	Outcome    string    `json:"outcome"`               // success、partial、skipped 或 failed
+	SkipReason string    `json:"skip_reason,omitempty"` // holiday、festival、weekend
+	Messages   int       `json:"messages"`              // 发送成功的消息数
+	Errors     []string  `json:"errors,omitempty"`
+	DryRun     bool      `json:"dry_run,omitempty"` // 消息没有发送到群
+}
+
+// Filter 查询条件，零值表示不限制
+type Filter struct {
+	Job     string
+	Group   string
+	Outcome string
+	From    time.Time // 开始时间不早于 From
+	To      time.Time // 开始时间早于 To
+	Limit   int       // 最多返回的条数
+}
+
+func (f Filter) match(r Run) bool {
+	return (f.Job == "" || r.Job == f.Job) &&
+		(f.Group == "" || r.Group == f.Group) &&
+		(f.Outcome == "" || r.Outcome == f.Outcome) &&
+		(f.From.IsZero() || !r.Start.Before(f.From)) &&
+		(f.To.IsZero() || r.Start.Before(f.To))
+}
+
+// Store 保存任务执行记录
+type Store struct {
+	mu   sync.Mutex
+	cfg  Config
+	runs []Run // 按开始时间排序
+	now  func() time.Time
+}
+
+var defaultStore = &Store{now: time.Now}
+
+// Open 从记录文件读取保留期内的记录，不修改文件，用于查询
+func Open(cfg Config) (*Store, error) {
+	s := &Store{cfg: cfg, now: time.Now}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Init 按配置初始化默认记录，从记录文件恢复保留期内的记录
+// 过期的记录在下次有记录过期时从文件中删除，避免与同时运行的其他命令争用文件
+func Init(cfg Config) error {
+	s, err := Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	defaultStore.mu.Lock()
+	defaultStore.cfg = s.cfg
+	defaultStore.runs = s.runs
+	defaultStore.mu.Unlock()
+	return nil
+}
+
+// load 从记录文件恢复记录
+func (s *Store) load() error {
+	if s.cfg.File == "" {
+		return nil
+	}
+
+	f, err := os.Open(s.cfg.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开任务执行记录文件失败: %w", err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Run
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			logrus.Warnf("跳过无法解析的任务执行记录: %v", err)
+			continue
+		}
+		s.runs = append(s.runs, r)
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取任务执行记录失败: %w", err)
+	}
+
+	sort.SliceStable(s.runs, func(i, j int) bool { return s.runs[i].Start.Before(s.runs[j].Start) })
+	s.prune()
+	return nil
+}
+
+// prune 删除超过保留天数的记录，返回是否删除了记录
+func (s *Store) prune() bool {
+	if s.cfg.RetentionDays <= 0 {
+		return false
+	}
+	cutoff := s.now().AddDate(0, 0, -s.cfg.RetentionDays)
+	i := sort.Search(len(s.runs), func(i int) bool { return !s.runs[i].Start.Before(cutoff) })
+	if i == 0 {
+		return false
+	}
+	s.runs = append([]Run(nil), s.runs[i:]...)
+	return true
+}
+
+// Add 记录任务执行结果并追加到记录文件，有记录过期时重写记录文件
+func (s *Store) Add(runs ...Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range runs {
+		// 同一任务的各群依次执行，通常已按开始时间排序
+		i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].Start.After(r.Start) })
+		s.runs = append(s.runs, Run{})
+		copy(s.runs[i+1:], s.runs[i:])
+		s.runs[i] = r
+	}
+
+	if s.cfg.File == "" {
+		s.prune()
+		return
+	}
+	if err := s.persist(runs); err != nil {
+		logrus.Errorf("写入任务执行记录失败: %v", err)
+	}
+}
+
+// persist 在文件锁内追加记录，有记录过期时重写记录文件
+// 其他进程（如 weatherrobot send）追加的记录不在内存中，重写前先从文件重新读取
+func (s *Store) persist(runs []Run) error {
+	unlock, err := filelock.Lock(s.cfg.File)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := appendRuns(s.cfg.File, runs); err != nil {
+		return err
+	}
+	if !s.prune() {
+		return nil
+	}
+	s.runs = nil
+	if err := s.load(); err != nil {
+		return err
+	}
+	return s.compact()
+}
+
+// Query 按条件返回记录，新的在前
+func (s *Store) Query(f Filter) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Run
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if !f.match(s.runs[i]) {
+			continue
+		}
+		result = append(result, s.runs[i])
+		if f.Limit > 0 && len(result) >= f.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// appendRuns 追加记录到文件
+func appendRuns(file string, runs []Run) error {
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, r := range runs {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact 用内存中的记录重写文件
+func (s *Store) compact() error {
+	if dir := filepath.Dir(s.cfg.File); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := s.cfg.File + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range s.runs {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.cfg.File)
+}
+
+// Add 在默认记录中记录任务执行结果
+func Add(runs ...Run) {
+	defaultStore.Add(runs...)
+}
+
+// Query 按条件查询默认记录，新的在前
+// 配置了记录文件时从文件读取，包含其他进程（如 weatherrobot send）写入的记录
+func Query(f Filter) []Run {
+	defaultStore.mu.Lock()
+	cfg := defaultStore.cfg
+	defaultStore.mu.Unlock()
+	if cfg.File == "" {
+		return defaultStore.Query(f)
+	}
+
+	s, err := Open(cfg)
+	if err != nil {
+		logrus.Warnf("读取任务执行记录失败，只返回本进程的记录: %v", err)
+		return defaultStore.Query(f)
+	}
+	return s.Query(f)
+}
+
+// 查询接口默认返回的条数
+const defaultLimit = 100
+
+// ParseFilter 解析查询参数：job、group、outcome、date（YYYY-MM-DD）或 days（最近几天）、limit
+func ParseFilter(job, group, outcome, date, days, limit string, now time.Time) (Filter, error) {
+	f := Filter{Job: job, Group: group, Outcome: outcome, Limit: defaultLimit}
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, now.Location())
+		if err != nil {
+			return f, fmt.Errorf("日期 %q 无效，应为 YYYY-MM-DD", date)
+		}
+		f.From, f.To = day, day.AddDate(0, 0, 1)
+	}
+	if days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("天数 %q 无效，应为正整数", days)
+		}
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		f.From = today.AddDate(0, 0, 1-n)
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("条数 %q 无效，应为非负整数", limit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// HandleHistory 以 JSON 返回任务执行记录，新的在前
+// 参数 job、group、outcome 筛选记录，date（YYYY-MM-DD）或 days 限定时间范围，limit 默认 100，0 表示不限制
+func HandleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, err := ParseFilter(q.Get("job"), q.Get("group"), q.Get("outcome"), q.Get("date"), q.Get("days"), q.Get("limit"), time.Now().In(holiday.Location()))
+	if err != nil {
+		http.Error(w, "invalid query: want date=YYYY-MM-DD, days=N, limit=N", http.StatusBadRequest)
+		return
+	}
+
+	runs := Query(f)
+	if runs == nil {
+		runs = []Run{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		logrus.Errorf("输出任务执行记录失败: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreRetention(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	s := &Store{cfg: Config{File: file, RetentionDays: 7}, now: func() time.Time { return now }}
+
+	s.Add(Run{Job: "daily_report", Group: "a", Start: now.AddDate(0, 0, -8), Outcome: "success"})
+	s.Add(
+		Run{Job: "daily_report", Group: "a", Start: now.AddDate(0, 0, -1), Outcome: "skipped", SkipReason: "weekend"},
+		Run{Job: "off_work", Group: "a", Start: now.Add(-time.Hour), Outcome: "failed", Errors: []string{"timeout"}},
+	)
+
+	// 8 天前的记录已过期，写入新记录时从文件中删除
+	reopened, err := Open(Config{File: file, RetentionDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := reopened.Query(Filter{})
+	if len(runs) != 2 || runs[0].Job != "off_work" || runs[1].SkipReason != "weekend" {
+		t.Fatalf("runs = %+v", runs)
+	}
+	data, _ := os.ReadFile(file)
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("file has %d lines, want 2", n)
+	}
+
+	if runs := s.Query(Filter{Outcome: "failed"}); len(runs) != 1 || runs[0].Errors[0] != "timeout" {
+		t.Errorf("failed runs = %+v", runs)
+	}
+	if runs := s.Query(Filter{From: now.Add(-2 * time.Hour)}); len(runs) != 1 {
+		t.Errorf("runs since 2h ago = %+v", runs)
+	}
+}
+
+func TestStoreKeepsRunsFromOtherProcesses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	cfg := Config{File: file, RetentionDays: 7}
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	server := &Store{cfg: cfg, now: clock}
+	cli := &Store{cfg: cfg, now: clock}
+
+	server.Add(Run{Job: "daily_report", Start: now.AddDate(0, 0, -6), Outcome: "success"})
+	cli.Add(Run{Job: "off_work", Trigger: TriggerManual, Start: now.Add(-time.Hour), Outcome: "success"})
+	// 两天后服务端的第一条记录过期，重写文件时不能丢掉命令行写入的记录
+	now = now.AddDate(0, 0, 2)
+	server.Add(Run{Job: "daily_report", Start: now, Outcome: "success"})
+
+	reopened, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := reopened.Query(Filter{})
+	if len(runs) != 2 || runs[1].Trigger != TriggerManual {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if runs := server.Query(Filter{Job: "off_work"}); len(runs) != 1 {
+		t.Errorf("server runs after compaction = %+v", runs)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	f, err := ParseFilter("off_work", "", "", "2026-10-16", "", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.From.Equal(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)) || !f.To.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) || f.Limit != defaultLimit {
+		t.Errorf("filter = %+v", f)
+	}
+	if f, _ := ParseFilter("", "", "", "", "3", "0", now); !f.From.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) || f.Limit != 0 {
+		t.Errorf("filter = %+v", f)
+	}
+	for _, bad := range [][3]string{{"10-16", "", ""}, {"", "0", ""}, {"", "", "-1"}} {
+		if _, err := ParseFilter("", "", "", bad[0], bad[1], bad[2], now); err == nil {
+			t.Errorf("ParseFilter(%q) should fail", bad)
+		}
+	}
+}
+
+func TestHandleHistory(t *testing.T) {
+	defer Init(Config{})
+	if err := Init(Config{}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	Add(
+		Run{Job: "daily_report", Group: "研发群", Start: start, Outcome: "success", Messages: 1},
+		Run{Job: "daily_report", Group: "销售群", Start: start.Add(time.Second), Outcome: "failed"},
+	)
+
+	w := httptest.NewRecorder()
+	HandleHistory(w, httptest.NewRequest(http.MethodGet, "/history?group=%E7%A0%94%E5%8F%91%E7%BE%A4", nil))
+	var runs []Run
+	if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Group != "研发群" || runs[0].Messages != 1 {
+		t.Errorf("runs = %+v", runs)
+	}
+
+	// 配置了记录文件时 /history 也返回其他进程写入的记录
+	cfg := Config{File: filepath.Join(t.TempDir(), "history.jsonl")}
+	if err := Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cli, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Add(Run{Job: "off_work", Trigger: TriggerManual, Start: start, Outcome: "success"})
+	w = httptest.NewRecorder()
+	HandleHistory(w, httptest.NewRequest(http.MethodGet, "/history?job=off_work", nil))
+	runs = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Trigger != TriggerManual {
+		t.Errorf("runs from file = %+v", runs)
+	}
+
+	w = httptest.NewRecorder()
+	HandleHistory(w, httptest.NewRequest(http.MethodGet, "/history?date=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
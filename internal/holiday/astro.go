@@ -0,0 +1,126 @@
+package holiday
+
+import (
+	"math"
+	"time"
+)
+
+// 天文计算：太阳视黄经，精度约 0.01°（约 15 分钟），参考 Meeus《天文算法》第 25 章低精度算法
+
+const (
+	unixEpochJD = 2440587.5 // 1970-01-01T00:00:00Z 的儒略日
+	j2000       = 2451545.0 // J2000.0 的儒略日
+	// 力学时与世界时之差（2020 年代约 69 秒），对日期计算的影响可以忽略不计
+	deltaT = 69.0 / 86400
+)
+
+// julianDay 返回时间对应的儒略日（世界时）
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + unixEpochJD
+}
+
+// timeOfJulianDay 把儒略日（世界时）转换为时间
+func timeOfJulianDay(jd float64) time.Time {
+	return time.Unix(0, int64((jd-unixEpochJD)*86400e9)).UTC()
+}
+
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// normalizeDegrees 把角度归一到 [0, 360)
+func normalizeDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// sunLongitude 返回儒略日（世界时）时刻太阳的视黄经（度）
+func sunLongitude(jd float64) float64 {
+	t := (jd + deltaT - j2000) / 36525
+	l0 := 280.46646 + 36000.76983*t + 0.0003032*t*t
+	m := rad(357.52911 + 35999.05029*t - 0.0001537*t*t)
+	c := (1.914602-0.004817*t-0.000014*t*t)*math.Sin(m) +
+		(0.019993-0.000101*t)*math.Sin(2*m) +
+		0.000289*math.Sin(3*m)
+	omega := rad(125.04 - 1934.136*t)
+	// 真黄经修正章动和光行差得到视黄经
+	return normalizeDegrees(l0 + c - 0.00569 - 0.00478*math.Sin(omega))
+}
+
+// solveSunLongitude 从 guess 附近求太阳视黄经等于 target 的时刻（儒略日）
+func solveSunLongitude(target, guess float64) float64 {
+	jd := guess
+	for i := 0; i < 50; i++ {
+		diff := math.Mod(target-sunLongitude(jd)+540, 360) - 180
+		// 太阳每天约运行 0.9856°
+		step := diff / 0.98564736
+		jd += step
+		if math.Abs(step) < 1e-7 {
+			break
+		}
+	}
+	return jd
+}
+
+// 朔望月平均长度（天）
+const synodicMonth = 29.530588861
+
+// newMoon 返回第 k 次朔（以 2000 年 1 月 6 日的朔为 0）的儒略日（世界时）
+// 参考 Meeus《天文算法》第 49 章，误差在 1 分钟以内
+func newMoon(k float64) float64 {
+	t := k / 1236.85
+	t2, t3, t4 := t*t, t*t*t, t*t*t*t
+	jde := 2451550.09766 + synodicMonth*k + 0.00015437*t2 - 0.000000150*t3 + 0.00000000073*t4
+
+	e := 1 - 0.002516*t - 0.0000074*t2
+	m := rad(2.5534 + 29.10535670*k - 0.0000014*t2 - 0.00000011*t3)
+	mp := rad(201.5643 + 385.81693528*k + 0.0107582*t2 + 0.00001238*t3 - 0.000000058*t4)
+	f := rad(160.7108 + 390.67050284*k - 0.0016118*t2 - 0.00000227*t3 + 0.000000011*t4)
+	omega := rad(124.7746 - 1.56375588*k + 0.0020672*t2 + 0.00000215*t3)
+
+	jde += -0.40720*math.Sin(mp) +
+		0.17241*e*math.Sin(m) +
+		0.01608*math.Sin(2*mp) +
+		0.01039*math.Sin(2*f) +
+		0.00739*e*math.Sin(mp-m) -
+		0.00514*e*math.Sin(mp+m) +
+		0.00208*e*e*math.Sin(2*m) -
+		0.00111*math.Sin(mp-2*f) -
+		0.00057*math.Sin(mp+2*f) +
+		0.00056*e*math.Sin(2*mp+m) -
+		0.00042*math.Sin(3*mp) +
+		0.00042*e*math.Sin(m+2*f) +
+		0.00038*e*math.Sin(m-2*f) -
+		0.00024*e*math.Sin(2*mp-m) -
+		0.00017*math.Sin(omega) -
+		0.00007*math.Sin(mp+2*m) +
+		0.00004*math.Sin(2*mp-2*f) +
+		0.00004*math.Sin(3*m) +
+		0.00003*math.Sin(mp+m-2*f) +
+		0.00003*math.Sin(2*mp+2*f) -
+		0.00003*math.Sin(mp+m+2*f) +
+		0.00003*math.Sin(mp-m+2*f) -
+		0.00002*math.Sin(mp-m-2*f) -
+		0.00002*math.Sin(3*mp+m) +
+		0.00002*math.Sin(4*mp)
+
+	// 行星摄动修正
+	planetary := []struct{ coeff, a0, a1 float64 }{
+		{0.000325, 299.77, 0.107408}, {0.000165, 251.88, 0.016321}, {0.000164, 251.83, 26.651886},
+		{0.000126, 349.42, 36.412478}, {0.000110, 84.66, 18.206239}, {0.000062, 141.74, 53.303771},
+		{0.000060, 207.14, 2.453732}, {0.000056, 154.84, 7.306860}, {0.000047, 34.52, 27.261239},
+		{0.000042, 207.19, 0.121824}, {0.000040, 291.34, 1.844379}, {0.000037, 161.72, 24.198154},
+		{0.000035, 239.56, 25.513099}, {0.000023, 331.55, 3.592518},
+	}
+	for i, p := range planetary {
+		a := p.a0 + p.a1*k
+		if i == 0 {
+			a -= 0.009173 * t2
+		}
+		jde += p.coeff * math.Sin(rad(a))
+	}
+	return jde - deltaT
+}
@@ -0,0 +1,347 @@
+package holiday
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCalendar 内置的中国大陆法定节假日日历
+const DefaultCalendar = "cn-mainland"
+
+// 查找节前最后一个工作日/节后第一个工作日时最多向前或向后看的天数
+const maxGapDays = 7
+
+// Calendar 一套节假日规则：节日（发送问候）、假期（静默）和调休补班日
+// 多个日历可以组合使用，如香港办公室使用 "hk" 加 "company"
+type Calendar struct {
+	Name             string
+	Festivals        []Festival
+	Holidays         []Holiday
+	AdjustedWorkdays []AdjustedWorkday
+}
+
+// CalendarConfig 自定义日历配置，条目可以写在配置中，也可以来自数据文件或 ICS 日历
+type CalendarConfig struct {
+	Name             string            `mapstructure:"name"`              // 日历名称，如 "hk"、"company"
+	File             string            `mapstructure:"file"`              // 节假日数据文件，格式同 data/cn-mainland.json
+	ICS              []string          `mapstructure:"ics"`               // ICS 日历（本地路径或 URL），事件作为假期导入
+	Festivals        []Festival        `mapstructure:"festivals"`         // 发送问候的节日
+	Rules            []FestivalRule    `mapstructure:"rules"`             // 按农历、节气或公历规则每年生成的节日
+	Holidays         []Holiday         `mapstructure:"holidays"`          // 不发送问候的假期
+	AdjustedWorkdays []AdjustedWorkday `mapstructure:"adjusted_workdays"` // 周末补班日
+}
+
+// 自定义日历，由 LoadCalendars 加载；cn-mainland 始终使用当前生效的节假日数据
+var (
+	calendarsMu sync.RWMutex
+	calendars   = map[string]*Calendar{}
+)
+
+// Default 返回内置的中国大陆日历
+func Default() *Calendar {
+	data := currentData()
+	return &Calendar{Name: DefaultCalendar, Festivals: data.Festivals, AdjustedWorkdays: data.AdjustedWorkdays}
+}
+
+// ValidateCalendarConfigs 校验自定义日历配置，返回所有问题
+func ValidateCalendarConfigs(cfgs []CalendarConfig) error {
+	var errs FieldErrors
+	seen := map[string]bool{DefaultCalendar: true}
+	for i, c := range cfgs {
+		path := fmt.Sprintf("calendars[%d]", i)
+		switch {
+		case c.Name == "":
+			errs.add(path+".name", "缺少 name")
+		case seen[c.Name]:
+			errs.add(path+".name", "日历 %s 重复定义", c.Name)
+		}
+		seen[c.Name] = true
+
+		for j, f := range c.Festivals {
+			if f.StartDate.IsZero() || f.EndDate.IsZero() || f.EndDate.Before(f.StartDate) {
+				errs.add(fmt.Sprintf("%s.festivals[%d]", path, j), "%s", named(f.Name, "日期无效"))
+			}
+		}
+		for j, r := range c.Rules {
+			errs.merge(fmt.Sprintf("%s.rules[%d]", path, j), r.errors())
+		}
+		errs.merge(path, holidayErrors("holidays", c.Holidays))
+		for j, d := range c.AdjustedWorkdays {
+			if d.Date.IsZero() {
+				errs.add(fmt.Sprintf("%s.adjusted_workdays[%d].date", path, j), "%s", named(d.Name, "缺少日期"))
+			}
+		}
+	}
+	return errs.err()
+}
+
+// LoadCalendars 加载自定义日历，ICS 中的重复事件展开到 [from, to] 范围内，
+// 规则生成 from 到 to 所在年份的节日
+func LoadCalendars(cfgs []CalendarConfig, from, to time.Time) error {
+	if err := ValidateCalendarConfigs(cfgs); err != nil {
+		return err
+	}
+
+	loaded := make(map[string]*Calendar, len(cfgs))
+	for _, c := range cfgs {
+		cal := &Calendar{
+			Name:             c.Name,
+			Festivals:        append([]Festival{}, c.Festivals...),
+			Holidays:         append([]Holiday{}, c.Holidays...),
+			AdjustedWorkdays: append([]AdjustedWorkday{}, c.AdjustedWorkdays...),
+		}
+		for _, r := range c.Rules {
+			cal.Festivals = append(cal.Festivals, r.FestivalsBetween(from.Year(), to.Year())...)
+		}
+		if c.File != "" {
+			b, err := os.ReadFile(c.File)
+			if err != nil {
+				return fmt.Errorf("日历 %s: 读取数据文件失败: %w", c.Name, err)
+			}
+			data, err := ParseData(b)
+			if err != nil {
+				return fmt.Errorf("日历 %s: %s: %w", c.Name, c.File, err)
+			}
+			cal.Festivals = append(cal.Festivals, data.Festivals...)
+			cal.AdjustedWorkdays = append(cal.AdjustedWorkdays, data.AdjustedWorkdays...)
+		}
+		for _, source := range c.ICS {
+			holidays, err := LoadICS(source, from, to)
+			if err != nil {
+				return fmt.Errorf("日历 %s: 导入 %s 失败: %w", c.Name, source, err)
+			}
+			cal.Holidays = append(cal.Holidays, holidays...)
+		}
+		cal.sort()
+		loaded[c.Name] = cal
+	}
+
+	calendarsMu.Lock()
+	calendars = loaded
+	calendarsMu.Unlock()
+	return nil
+}
+
+// CalendarNames 返回所有可用的日历名称
+func CalendarNames() []string {
+	calendarsMu.RLock()
+	defer calendarsMu.RUnlock()
+	names := []string{DefaultCalendar}
+	for name := range calendars {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Lookup 按名称组合日历，names 为空时返回中国大陆日历
+func Lookup(names []string) (*Calendar, error) {
+	if len(names) == 0 {
+		return Default(), nil
+	}
+
+	calendarsMu.RLock()
+	defer calendarsMu.RUnlock()
+	parts := make([]*Calendar, 0, len(names))
+	for _, name := range names {
+		if name == DefaultCalendar {
+			parts = append(parts, Default())
+			continue
+		}
+		cal, ok := calendars[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的日历 %s", name)
+		}
+		parts = append(parts, cal)
+	}
+	return Compose(parts...), nil
+}
+
+// Compose 把多个日历合并为一个，名称用 "+" 连接
+func Compose(parts ...*Calendar) *Calendar {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	names := make([]string, len(parts))
+	composed := &Calendar{}
+	for i, p := range parts {
+		names[i] = p.Name
+		composed.Festivals = append(composed.Festivals, p.Festivals...)
+		composed.Holidays = append(composed.Holidays, p.Holidays...)
+		composed.AdjustedWorkdays = append(composed.AdjustedWorkdays, p.AdjustedWorkdays...)
+	}
+	composed.Name = strings.Join(names, "+")
+	composed.sort()
+	return composed
+}
+
+func (c *Calendar) sort() {
+	sort.SliceStable(c.Festivals, func(i, j int) bool {
+		return c.Festivals[i].StartDate.Before(c.Festivals[j].StartDate)
+	})
+	sort.SliceStable(c.AdjustedWorkdays, func(i, j int) bool {
+		return c.AdjustedWorkdays[i].Date.Before(c.AdjustedWorkdays[j].Date)
+	})
+}
+
+// IsAdjustedWorkday 检查是否为调休补班日，返回补班日信息
+func (c *Calendar) IsAdjustedWorkday(t time.Time) (bool, *AdjustedWorkday) {
+	date := DateOf(t)
+	for i, day := range c.AdjustedWorkdays {
+		if day.Date == date {
+			return true, &c.AdjustedWorkdays[i]
+		}
+	}
+	return false, nil
+}
+
+// IsWorkday 检查是否为工作日（周一到周五，或调休补班日）
+func (c *Calendar) IsWorkday(t time.Time) bool {
+	if isAdjusted, _ := c.IsAdjustedWorkday(t); isAdjusted {
+		return true
+	}
+
+	weekday := DateOf(t).Weekday()
+	// 0 = Sunday, 1 = Monday, ..., 6 = Saturday
+	return weekday >= 1 && weekday <= 5
+}
+
+// IsFestival 检查是否为节假日，返回节假日信息及特色问候
+func (c *Calendar) IsFestival(t time.Time) (bool, *Festival) {
+	date := DateOf(t)
+	for i, festival := range c.Festivals {
+		if festival.Contains(date) {
+			return true, &c.Festivals[i]
+		}
+	}
+	return false, nil
+}
+
+// isHoliday 检查是否在日历自带的假期、传入的自定义假期或 ICS 导入的假期中
+func (c *Calendar) isHoliday(t time.Time, holidays []Holiday) bool {
+	return IsHoliday(t, c.Holidays) || IsHoliday(t, holidays) || IsHoliday(t, ImportedHolidays())
+}
+
+// 不需要上班的原因，写入定时任务的执行记录
+const (
+	SkipHoliday  = "holiday"  // 自定义假期或 ICS 导入的假期
+	SkipFestival = "festival" // 法定节假日
+	SkipWeekend  = "weekend"  // 周末（非调休补班日）
+)
+
+// SkipReason 返回 t 不需要上班的原因，需要上班时返回空字符串
+func (c *Calendar) SkipReason(t time.Time, holidays []Holiday) string {
+	if c.isHoliday(t, holidays) {
+		return SkipHoliday
+	}
+	if isFestival, _ := c.IsFestival(t); isFestival {
+		return SkipFestival
+	}
+	if !c.IsWorkday(t) {
+		return SkipWeekend
+	}
+	return ""
+}
+
+// ShouldSendReminder 检查是否应该发送提醒
+// 返回: (是否发送, 是否为节假日, 节假日信息)
+func (c *Calendar) ShouldSendReminder(clock Clock, holidays []Holiday) (bool, bool, *Festival) {
+	now := clock.Now()
+
+	// 检查是否在假期期间（含日历自带和 ICS 导入的假期）
+	if c.isHoliday(now, holidays) {
+		return false, false, nil
+	}
+
+	// 检查是否为节假日
+	isFestival, festival := c.IsFestival(now)
+	if isFestival {
+		// 节假日: 不发送普通报告，但发送节假日特色问候
+		return false, true, festival
+	}
+
+	// 检查是否为工作日（含调休补班日）
+	if c.IsWorkday(now) {
+		return true, false, nil
+	}
+
+	// 周末不提醒
+	return false, false, nil
+}
+
+// ShouldSendOffWorkReminder 检查是否应该发送下班提醒
+// 返回: (是否发送, 是否为节假日, 节假日信息)
+func (c *Calendar) ShouldSendOffWorkReminder(clock Clock, holidays []Holiday) (bool, bool, *Festival) {
+	now := clock.Now()
+
+	// 检查是否在假期期间（含日历自带和 ICS 导入的假期）
+	if c.isHoliday(now, holidays) {
+		return false, false, nil
+	}
+
+	// 检查是否为节假日（节假日不发送下班提醒）
+	isFestival, _ := c.IsFestival(now)
+	if isFestival {
+		return false, false, nil
+	}
+
+	// 检查是否为工作日（含调休补班日）
+	if c.IsWorkday(now) {
+		return true, false, nil
+	}
+
+	// 周末不提醒
+	return false, false, nil
+}
+
+// IsWorkingDay 检查 t 是否需要上班：工作日（含调休补班日），且不在节假日或假期中
+func (c *Calendar) IsWorkingDay(t time.Time, holidays []Holiday) bool {
+	if c.isHoliday(t, holidays) {
+		return false
+	}
+	if isFestival, _ := c.IsFestival(t); isFestival {
+		return false
+	}
+	return c.IsWorkday(t)
+}
+
+// UpcomingFestival 如果 t 是节假日前的最后一个工作日，返回即将开始的节假日
+func (c *Calendar) UpcomingFestival(t time.Time, holidays []Holiday) *Festival {
+	if !c.IsWorkingDay(t, holidays) {
+		return nil
+	}
+
+	for d := 1; d <= maxGapDays; d++ {
+		day := t.AddDate(0, 0, d)
+		if isFestival, festival := c.IsFestival(day); isFestival && festival.IsFirstDay(day) {
+			return festival
+		}
+		if c.IsWorkingDay(day, holidays) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// EndedFestival 如果 t 是节假日后的第一个工作日，返回刚结束的节假日
+func (c *Calendar) EndedFestival(t time.Time, holidays []Holiday) *Festival {
+	if !c.IsWorkingDay(t, holidays) {
+		return nil
+	}
+
+	for d := 1; d <= maxGapDays; d++ {
+		day := t.AddDate(0, 0, -d)
+		if isFestival, festival := c.IsFestival(day); isFestival {
+			return festival
+		}
+		if c.IsWorkingDay(day, holidays) {
+			return nil
+		}
+	}
+	return nil
+}
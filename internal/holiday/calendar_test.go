@@ -0,0 +1,82 @@
+package holiday
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadTestCalendars(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	hkData := `{"version": "test", "years": [2026], "festivals": [
+		{"name": "國慶日", "start_date": "2026-10-01", "end_date": "2026-10-01", "greeting": "國慶日快樂！"},
+		{"name": "重陽節", "start_date": "2026-10-19", "end_date": "2026-10-19", "greeting": "重陽節快樂！"}
+	]}`
+	file := filepath.Join(dir, "hk.json")
+	if err := os.WriteFile(file, []byte(hkData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfgs := []CalendarConfig{
+		{Name: "hk", File: file},
+		{Name: "company", Holidays: []Holiday{{Name: "公司周年庆", StartDate: mustDate("2026-06-18"), EndDate: mustDate("2026-06-18")}}},
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, Location())
+	if err := LoadCalendars(cfgs, from, from.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("LoadCalendars: %v", err)
+	}
+	t.Cleanup(func() { LoadCalendars(nil, from, from) })
+}
+
+func TestComposedCalendar(t *testing.T) {
+	loadTestCalendars(t)
+
+	hk, err := Lookup([]string{"hk", "company"})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if hk.Name != "hk+company" {
+		t.Errorf("Name = %q", hk.Name)
+	}
+
+	tests := []struct {
+		name       string
+		date       string
+		cal        *Calendar
+		shouldSend bool
+		isFestival bool
+	}{
+		{"HK National Day", "2026-10-01", hk, false, true},
+		{"HK works on mainland Golden Week", "2026-10-02", hk, true, false},
+		{"Mainland Golden Week", "2026-10-02", Default(), false, true},
+		{"HK Chung Yeung", "2026-10-19", hk, false, true},
+		{"Company anniversary", "2026-06-18", hk, false, false},
+		{"Mainland ignores company calendar", "2026-06-18", Default(), true, false},
+		{"No adjusted workdays in HK", "2026-02-14", hk, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := FixedClock(mustDate(tt.date).Time().Add(8 * time.Hour))
+			shouldSend, isFestival, _ := tt.cal.ShouldSendReminder(clock, nil)
+			if shouldSend != tt.shouldSend || isFestival != tt.isFestival {
+				t.Errorf("ShouldSendReminder = (%v, %v), want (%v, %v)", shouldSend, isFestival, tt.shouldSend, tt.isFestival)
+			}
+		})
+	}
+
+	if _, err := Lookup([]string{"sg"}); err == nil {
+		t.Error("unknown calendar should fail")
+	}
+}
+
+func TestValidateCalendarConfigs(t *testing.T) {
+	if err := ValidateCalendarConfigs([]CalendarConfig{{Name: DefaultCalendar}}); err == nil {
+		t.Error("redefining cn-mainland should fail")
+	}
+	bad := []CalendarConfig{{Name: "company", Holidays: []Holiday{{Name: "x", StartDate: mustDate("2026-06-18")}}}}
+	if err := ValidateCalendarConfigs(bad); err == nil {
+		t.Error("holiday without end date should fail")
+	}
+}
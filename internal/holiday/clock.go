@@ -0,0 +1,37 @@
+package holiday
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock 时间来源，便于测试时固定"今天"
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 使用系统时间
+type SystemClock struct{}
+
+// Now 返回配置时区的当前时间
+func (SystemClock) Now() time.Time {
+	return time.Now().In(location)
+}
+
+// FixedClock 总是返回固定时间
+type FixedClock time.Time
+
+// Now 返回固定时间
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// ParseNow 解析 --now 参数，支持 "2006-01-02" 和 "2006-01-02 15:04"，按配置时区解释
+func ParseNow(s string) (Clock, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return FixedClock(t), nil
+		}
+	}
+	return nil, fmt.Errorf("无效的时间 %q，格式应为 YYYY-MM-DD 或 \"YYYY-MM-DD HH:MM\"", s)
+}
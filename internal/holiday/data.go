@@ -0,0 +1,189 @@
+package holiday
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 内置的中国大陆节假日数据，新一年的放假通知发布后更新该文件
+//
+//go:embed data/cn-mainland.json
+var builtinData []byte
+
+// CalendarData 节假日数据文件格式
+type CalendarData struct {
+	Version          string            `json:"version"`           // 数据版本（通常为放假通知的发布日期）
+	Source           string            `json:"source"`            // 数据来源
+	Years            []int             `json:"years"`             // 覆盖的年份
+	Festivals        []Festival        `json:"festivals"`         // 法定节假日（含调休后的完整假期）
+	AdjustedWorkdays []AdjustedWorkday `json:"adjusted_workdays"` // 调休补班日
+}
+
+// DataConfig 节假日数据配置
+type DataConfig struct {
+	File     string `mapstructure:"file"`      // 覆盖内置数据的文件，格式同 data/cn-mainland.json
+	FetchURL string `mapstructure:"fetch_url"` // 在线数据地址，{year} 会替换为年份，为空则不拉取
+	CacheDir string `mapstructure:"cache_dir"` // 在线数据的本地缓存目录
+}
+
+// 当前生效的数据，中国大陆日历的节日和补班日来自这里
+// 配置热加载时在其他 goroutine 中替换，读写都要经过 dataMu
+var (
+	dataMu  sync.RWMutex
+	current *CalendarData
+)
+
+func init() {
+	data, err := ParseData(builtinData)
+	if err != nil {
+		panic(fmt.Sprintf("内置节假日数据无效: %v", err))
+	}
+	apply(data)
+}
+
+// ParseData 解析并校验节假日数据
+func ParseData(b []byte) (*CalendarData, error) {
+	var data CalendarData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("解析节假日数据失败: %w", err)
+	}
+
+	// 日期格式在 json.Unmarshal 时已校验，这里检查缺失和先后顺序
+	for i, f := range data.Festivals {
+		if f.StartDate.IsZero() || f.EndDate.IsZero() {
+			return nil, fmt.Errorf("festivals[%d] %s 缺少开始或结束日期", i, f.Name)
+		}
+		if f.EndDate.Before(f.StartDate) {
+			return nil, fmt.Errorf("festivals[%d] %s 结束日期早于开始日期", i, f.Name)
+		}
+	}
+	for i, d := range data.AdjustedWorkdays {
+		if d.Date.IsZero() {
+			return nil, fmt.Errorf("adjusted_workdays[%d] %s 缺少日期", i, d.Name)
+		}
+	}
+
+	if len(data.Years) == 0 {
+		data.Years = yearsOf(&data)
+	}
+	return &data, nil
+}
+
+// yearsOf 根据节假日推断数据覆盖的年份
+func yearsOf(data *CalendarData) []int {
+	seen := make(map[int]bool)
+	var years []int
+	for _, f := range data.Festivals {
+		if year := f.StartDate.Year; !seen[year] {
+			seen[year] = true
+			years = append(years, year)
+		}
+	}
+	sort.Ints(years)
+	return years
+}
+
+// HasYear 检查数据是否覆盖某一年
+func (d *CalendarData) HasYear(year int) bool {
+	for _, y := range d.Years {
+		if y == year {
+			return true
+		}
+	}
+	return false
+}
+
+// merge 合并另一份数据（通常是某一年的在线数据）
+func (d *CalendarData) merge(other *CalendarData) {
+	for _, y := range other.Years {
+		if !d.HasYear(y) {
+			d.Years = append(d.Years, y)
+		}
+	}
+	sort.Ints(d.Years)
+
+	d.Festivals = append(d.Festivals, other.Festivals...)
+	sort.SliceStable(d.Festivals, func(i, j int) bool {
+		return d.Festivals[i].StartDate.Before(d.Festivals[j].StartDate)
+	})
+	d.AdjustedWorkdays = append(d.AdjustedWorkdays, other.AdjustedWorkdays...)
+	sort.SliceStable(d.AdjustedWorkdays, func(i, j int) bool {
+		return d.AdjustedWorkdays[i].Date.Before(d.AdjustedWorkdays[j].Date)
+	})
+}
+
+// apply 使数据生效，生效后 data 不再修改
+func apply(data *CalendarData) {
+	dataMu.Lock()
+	current = data
+	dataMu.Unlock()
+}
+
+// currentData 返回当前生效的数据，调用方不能修改
+func currentData() *CalendarData {
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	return current
+}
+
+// HasYear 检查当前生效的数据是否覆盖某一年
+func HasYear(year int) bool {
+	return currentData().HasYear(year)
+}
+
+// DataVersion 返回当前生效数据的版本和覆盖年份
+func DataVersion() (string, []int) {
+	data := currentData()
+	return data.Version, data.Years
+}
+
+// LoadCalendar 按配置加载节假日数据
+// 优先使用 cfg.File，否则使用内置数据；今年或明年的数据缺失时尝试读取缓存或在线拉取，
+// 仍然缺失则输出警告（缺失年份的节假日会被当作普通工作日）
+func LoadCalendar(cfg DataConfig, now time.Time) error {
+	data, err := ParseData(builtinData)
+	if err != nil {
+		return err
+	}
+
+	if cfg.File != "" {
+		b, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return fmt.Errorf("读取节假日数据文件失败: %w", err)
+		}
+		if data, err = ParseData(b); err != nil {
+			return fmt.Errorf("%s: %w", cfg.File, err)
+		}
+	}
+
+	for _, year := range []int{now.Year(), now.Year() + 1} {
+		if data.HasYear(year) {
+			continue
+		}
+		fetched, err := loadYear(cfg, year)
+		if err != nil {
+			logrus.Warnf("获取 %d 年节假日数据失败: %v", year, err)
+			continue
+		}
+		data.merge(fetched)
+		logrus.Infof("已加载 %d 年节假日数据（%s）", year, fetched.Source)
+	}
+
+	apply(data)
+	logrus.Infof("节假日数据版本 %s，覆盖年份 %v", data.Version, data.Years)
+
+	if !data.HasYear(now.Year()) {
+		logrus.Errorf("缺少 %d 年节假日数据，今年的法定节假日将被当作普通工作日，请更新节假日数据", now.Year())
+	}
+	if !data.HasYear(now.Year() + 1) {
+		logrus.Warnf("缺少 %d 年节假日数据，国务院放假通知发布后请更新节假日数据", now.Year()+1)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package holiday
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 2027 年的示例数据（holiday-cn 格式），仅用于测试
+const sampleHolidayCN = `{
+	"year": 2027,
+	"papers": ["测试放假通知"],
+	"days": [
+		{"name": "元旦", "date": "2027-01-01", "isOffDay": true},
+		{"name": "春节", "date": "2027-02-06", "isOffDay": true},
+		{"name": "春节", "date": "2027-02-05", "isOffDay": true},
+		{"name": "春节", "date": "2027-02-07", "isOffDay": true},
+		{"name": "春节", "date": "2027-02-20", "isOffDay": false}
+	]
+}`
+
+func TestBuiltinData(t *testing.T) {
+	for _, year := range []int{2024, 2025, 2026} {
+		if !HasYear(year) {
+			t.Errorf("builtin data should cover %d", year)
+		}
+	}
+	if cal := Default(); len(cal.Festivals) == 0 || len(cal.AdjustedWorkdays) == 0 {
+		t.Error("builtin festivals and adjusted workdays should not be empty")
+	}
+}
+
+func TestParseDataInvalidDate(t *testing.T) {
+	_, err := ParseData([]byte(`{"festivals": [{"name": "元旦", "start_date": "2027-13-01", "end_date": "2027-01-01"}]}`))
+	if err == nil {
+		t.Fatal("expected error for invalid date")
+	}
+}
+
+func TestParseHolidayCN(t *testing.T) {
+	data, err := parseHolidayCN([]byte(sampleHolidayCN))
+	if err != nil {
+		t.Fatalf("parseHolidayCN error: %v", err)
+	}
+	if len(data.Festivals) != 2 {
+		t.Fatalf("got %d festivals, want 2", len(data.Festivals))
+	}
+	spring := data.Festivals[1]
+	if spring.StartDate.String() != "2027-02-05" || spring.EndDate.String() != "2027-02-07" {
+		t.Errorf("spring festival = %s..%s, want 2027-02-05..2027-02-07", spring.StartDate, spring.EndDate)
+	}
+	if spring.Greeting == "" {
+		t.Error("greeting should be filled from defaults")
+	}
+	if len(data.AdjustedWorkdays) != 1 || data.AdjustedWorkdays[0].Date.String() != "2027-02-20" {
+		t.Errorf("adjusted workdays = %v, want [2027-02-20]", data.AdjustedWorkdays)
+	}
+}
+
+func TestLoadCalendarFromCache(t *testing.T) {
+	defer LoadCalendar(DataConfig{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2027.json"), []byte(sampleHolidayCN), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadCalendar(DataConfig{CacheDir: dir}, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("LoadCalendar error: %v", err)
+	}
+	if !HasYear(2026) || !HasYear(2027) {
+		t.Error("calendar should cover 2026 and 2027")
+	}
+	if isFestival, f := IsFestival(time.Date(2027, 2, 6, 8, 0, 0, 0, time.UTC)); !isFestival || f.Name != "春节" {
+		t.Error("2027-02-06 should be 春节")
+	}
+	if !IsWorkday(time.Date(2027, 2, 20, 8, 0, 0, 0, time.UTC)) {
+		t.Error("2027-02-20 should be an adjusted workday")
+	}
+}
+
+func TestLoadCalendarOverrideFile(t *testing.T) {
+	defer LoadCalendar(DataConfig{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	file := filepath.Join(t.TempDir(), "holidays.json")
+	override := `{"version": "test", "years": [2026], "festivals": [{"name": "公司假", "start_date": "2026-03-02", "end_date": "2026-03-02", "greeting": "休息"}]}`
+	if err := os.WriteFile(file, []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadCalendar(DataConfig{File: file}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("LoadCalendar error: %v", err)
+	}
+	if version, _ := DataVersion(); version != "test" {
+		t.Errorf("version = %s, want test", version)
+	}
+	if isFestival, _ := IsFestival(time.Date(2026, 2, 16, 8, 0, 0, 0, time.UTC)); isFestival {
+		t.Error("override file should replace builtin festivals")
+	}
+}
+
+// 配置热加载与定时任务、HTTP 接口同时读取节假日数据，用 go test -race 检查
+func TestReloadWhileLookup(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer LoadCalendar(DataConfig{}, now)
+	defer ImportICS(nil, now, now)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			LoadCalendar(DataConfig{}, now)
+			ImportICS(nil, now, now)
+		}
+	}()
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, Location())
+	for i := 0; i < 200; i++ {
+		IsFestival(day)
+		IsHoliday(day, ImportedHolidays())
+		ShouldSendReminder(FixedClock(day), nil)
+		HasYear(2026)
+	}
+	<-done
+}
@@ -0,0 +1,113 @@
+package holiday
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // 内置时区数据，精简镜像中没有 /usr/share/zoneinfo 也能加载时区
+)
+
+// DefaultTimezone 默认时区，节假日都按中国大陆的日期判断
+const DefaultTimezone = "Asia/Shanghai"
+
+// 判断日期使用的时区，由配置 timezone 设置
+var location = mustLoadLocation(DefaultTimezone)
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("加载时区 %s 失败: %v", name, err))
+	}
+	return loc
+}
+
+// Location 返回判断日期使用的时区
+func Location() *time.Location {
+	return location
+}
+
+// SetLocation 设置判断日期使用的时区
+func SetLocation(loc *time.Location) {
+	if loc != nil {
+		location = loc
+	}
+}
+
+// Date 公历日期，不含时间和时区
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate 解析 YYYY-MM-DD 格式的日期，拒绝 2025-02-30 这类不存在的日期
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return Date{}, fmt.Errorf("日期 %q 无效，格式应为 YYYY-MM-DD", s)
+	}
+	return Date{t.Year(), t.Month(), t.Day()}, nil
+}
+
+// DateOf 返回 t 在配置时区中的日期
+func DateOf(t time.Time) Date {
+	t = t.In(location)
+	return Date{t.Year(), t.Month(), t.Day()}
+}
+
+// Time 返回该日期在配置时区中的零点
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, location)
+}
+
+// String 返回 YYYY-MM-DD 格式
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero 检查是否为零值（未设置）
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// AddDays 返回 n 天后的日期
+func (d Date) AddDays(n int) Date {
+	t := time.Date(d.Year, d.Month, d.Day+n, 0, 0, 0, 0, time.UTC)
+	return Date{t.Year(), t.Month(), t.Day()}
+}
+
+// Sub 返回 d 与 other 相差的天数
+func (d Date) Sub(other Date) int {
+	a := time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+	b := time.Date(other.Year, other.Month, other.Day, 0, 0, 0, 0, time.UTC)
+	return int(a.Sub(b).Hours() / 24)
+}
+
+// Before 检查 d 是否早于 other
+func (d Date) Before(other Date) bool {
+	return d.Sub(other) < 0
+}
+
+// After 检查 d 是否晚于 other
+func (d Date) After(other Date) bool {
+	return d.Sub(other) > 0
+}
+
+// Weekday 返回星期几
+func (d Date) Weekday() time.Weekday {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC).Weekday()
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (d *Date) UnmarshalText(b []byte) error {
+	parsed, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,57 @@
+package holiday
+
+import (
+	"testing"
+	"time"
+)
+
+func mustDate(s string) Date {
+	d, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("2026-02-28")
+	if err != nil {
+		t.Fatalf("ParseDate: %v", err)
+	}
+	if d.AddDays(1).String() != "2026-03-01" || d.AddDays(1).Sub(d) != 1 {
+		t.Errorf("AddDays(1) = %s", d.AddDays(1))
+	}
+	for _, bad := range []string{"2026-02-30", "2026-2-1", "2026/02/01", ""} {
+		if _, err := ParseDate(bad); err == nil {
+			t.Errorf("ParseDate(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDateOfUsesConfiguredLocation(t *testing.T) {
+	// 北京时间 10 月 1 日 01:00，UTC 仍是 9 月 30 日
+	utc := time.Date(2026, 9, 30, 17, 0, 0, 0, time.UTC)
+	if got := DateOf(utc).String(); got != "2026-10-01" {
+		t.Fatalf("DateOf = %s, want 2026-10-01", got)
+	}
+	if ok, f := IsFestival(utc); !ok || f.Name != "国庆节" {
+		t.Error("2026-09-30T17:00Z should be 国庆节 in Asia/Shanghai")
+	}
+	if !IsHoliday(utc, []Holiday{{Name: "x", StartDate: mustDate("2026-10-01"), EndDate: mustDate("2026-10-01")}}) {
+		t.Error("custom holiday should match the Shanghai date")
+	}
+}
+
+func TestValidateHolidays(t *testing.T) {
+	valid := []Holiday{{Name: "年假", StartDate: mustDate("2026-03-01"), EndDate: mustDate("2026-03-01")}}
+	if err := ValidateHolidays(valid); err != nil {
+		t.Errorf("ValidateHolidays(valid) = %v", err)
+	}
+	reversed := []Holiday{{Name: "年假", StartDate: mustDate("2026-03-05"), EndDate: mustDate("2026-03-01")}}
+	if err := ValidateHolidays(reversed); err == nil {
+		t.Error("end before start should be rejected")
+	}
+	if err := ValidateHolidays([]Holiday{{Name: "年假", StartDate: mustDate("2026-03-05")}}); err == nil {
+		t.Error("missing end date should be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+package holiday
+
+import (
+	"fmt"
+	"time"
+)
+
+// IsFirstDay 检查 t 是否为节假日的第一天
+func (f Festival) IsFirstDay(t time.Time) bool {
+	return DateOf(t) == f.StartDate
+}
+
+// Days 节假日的天数
+func (f Festival) Days() int {
+	return f.EndDate.Sub(f.StartDate) + 1
+}
+
+// Period 节假日的日期范围，如 "10月1日至10月7日，共7天"
+func (f Festival) Period() string {
+	start, end := f.StartDate, f.EndDate
+	if start == end {
+		return fmt.Sprintf("%d月%d日，共1天", start.Month, start.Day)
+	}
+	return fmt.Sprintf("%d月%d日至%d月%d日，共%d天", start.Month, start.Day, end.Month, end.Day, f.Days())
+}
+
+// GreetingMessage 节假日第一天发送的问候
+func (f Festival) GreetingMessage() string {
+	return fmt.Sprintf("%s\n\n%s假期（%s）期间天气报告暂停，假期结束后继续为您服务。", f.Greeting, f.Name, f.Period())
+}
+
+// PreHolidayMessage 节前最后一个工作日附在天气报告中的放假提醒
+func (f Festival) PreHolidayMessage() string {
+	return fmt.Sprintf("📢 %s假期（%s）即将开始，假期期间天气报告暂停。站好最后一班岗，祝大家假期愉快、出行平安！", f.Name, f.Period())
+}
+
+// IsWorkingDay 检查 t 是否需要上班：工作日（含调休补班日），且不在节假日或假期中（中国大陆日历）
+func IsWorkingDay(t time.Time, holidays []Holiday) bool {
+	return Default().IsWorkingDay(t, holidays)
+}
+
+// UpcomingFestival 如果 t 是节假日前的最后一个工作日，返回即将开始的节假日（中国大陆日历）
+func UpcomingFestival(t time.Time, holidays []Holiday) *Festival {
+	return Default().UpcomingFestival(t, holidays)
+}
+
+// EndedFestival 如果 t 是节假日后的第一个工作日，返回刚结束的节假日（中国大陆日历）
+func EndedFestival(t time.Time, holidays []Holiday) *Festival {
+	return Default().EndedFestival(t, holidays)
+}
@@ -0,0 +1,54 @@
+package holiday
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFestivalPeriod(t *testing.T) {
+	f := Festival{Name: "国庆节", StartDate: mustDate("2026-10-01"), EndDate: mustDate("2026-10-07")}
+	if got := f.Period(); got != "10月1日至10月7日，共7天" {
+		t.Errorf("Period() = %q", got)
+	}
+	if f.Days() != 7 {
+		t.Errorf("Days() = %d, want 7", f.Days())
+	}
+	if !f.IsFirstDay(time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)) || f.IsFirstDay(time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Error("IsFirstDay should only match 2026-10-01")
+	}
+}
+
+func TestUpcomingAndEndedFestival(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     time.Time
+		upcoming string
+		ended    string
+	}{
+		{"Day before National Day", time.Date(2026, 9, 30, 8, 0, 0, 0, time.UTC), "国庆节", ""},
+		{"Two days before National Day", time.Date(2026, 9, 29, 8, 0, 0, 0, time.UTC), "", ""},
+		{"First day after National Day", time.Date(2026, 10, 8, 8, 0, 0, 0, time.UTC), "", "国庆节"},
+		{"Second day after National Day", time.Date(2026, 10, 9, 8, 0, 0, 0, time.UTC), "", ""},
+		{"Adjusted workday before Spring Festival", time.Date(2026, 2, 14, 8, 0, 0, 0, time.UTC), "春节", ""},
+		{"Friday before adjusted workday", time.Date(2026, 2, 13, 8, 0, 0, 0, time.UTC), "", ""},
+		{"First day after Spring Festival", time.Date(2026, 2, 24, 8, 0, 0, 0, time.UTC), "", "春节"},
+		{"During Spring Festival", time.Date(2026, 2, 17, 8, 0, 0, 0, time.UTC), "", ""},
+		{"Friday before Qingming weekend", time.Date(2026, 4, 3, 8, 0, 0, 0, time.UTC), "清明节", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upcoming := ""
+			if f := UpcomingFestival(tt.date, nil); f != nil {
+				upcoming = f.Name
+			}
+			ended := ""
+			if f := EndedFestival(tt.date, nil); f != nil {
+				ended = f.Name
+			}
+			if upcoming != tt.upcoming || ended != tt.ended {
+				t.Errorf("upcoming=%q ended=%q, want %q %q", upcoming, ended, tt.upcoming, tt.ended)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package holiday
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// holidayCNYear holiday-cn（https://github.com/NateScarlet/holiday-cn）的年度数据格式，
+// 由国务院放假通知整理而来，isOffDay 为 false 的日期是调休补班日
+type holidayCNYear struct {
+	Year   int      `json:"year"`
+	Papers []string `json:"papers"`
+	Days   []struct {
+		Name     string `json:"name"`
+		Date     string `json:"date"`
+		IsOffDay bool   `json:"isOffDay"`
+	} `json:"days"`
+}
+
+// 在线数据没有问候语，按节日名称补充
+var defaultGreetings = []struct {
+	Name     string
+	Greeting string
+}{
+	{"元旦", "🎉 新年快乐！祝大家元旦假期开心！"},
+	{"春节", "🧧 春节快乐！祝大家新春快乐，恭喜发财！"},
+	{"清明", "🌿 清明节安康！缅怀先人，珍惜当下。"},
+	{"劳动", "💪 劳动节快乐！感谢所有劳动者的付出！"},
+	{"端午", "🐉 端午节快乐！祝大家粽子香，生活甜！"},
+	{"国庆", "🎊 国庆节快乐！祝伟大祖国繁荣昌盛！"},
+	{"中秋", "🌕 中秋节快乐！祝大家月圆人圆事圆满！"},
+}
+
+// greetingFor 返回节日的默认问候语
+func greetingFor(name string) string {
+	for _, g := range defaultGreetings {
+		if strings.Contains(name, g.Name) {
+			return g.Greeting
+		}
+	}
+	return fmt.Sprintf("🎈 %s快乐！祝大家假期愉快！", name)
+}
+
+// parseHolidayCN 把 holiday-cn 格式转换为 CalendarData，连续的同名放假日合并为一个节假日
+func parseHolidayCN(b []byte) (*CalendarData, error) {
+	var raw holidayCNYear
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("解析在线节假日数据失败: %w", err)
+	}
+	if raw.Year == 0 || len(raw.Days) == 0 {
+		return nil, fmt.Errorf("在线节假日数据为空")
+	}
+
+	days := raw.Days
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	data := &CalendarData{
+		Version: time.Now().Format("2006-01-02"),
+		Source:  "holiday-cn",
+		Years:   []int{raw.Year},
+	}
+	if len(raw.Papers) > 0 {
+		data.Source = raw.Papers[len(raw.Papers)-1]
+	}
+
+	for _, day := range days {
+		date, err := ParseDate(day.Date)
+		if err != nil {
+			return nil, fmt.Errorf("在线节假日数据%w", err)
+		}
+
+		if !day.IsOffDay {
+			data.AdjustedWorkdays = append(data.AdjustedWorkdays, AdjustedWorkday{Date: date, Name: day.Name})
+			continue
+		}
+
+		// 与上一个节假日同名且日期连续则延长
+		if n := len(data.Festivals); n > 0 {
+			last := &data.Festivals[n-1]
+			if last.Name == day.Name && last.EndDate.AddDays(1) == date {
+				last.EndDate = date
+				continue
+			}
+		}
+		data.Festivals = append(data.Festivals, Festival{
+			Name:      day.Name,
+			StartDate: date,
+			EndDate:   date,
+			Greeting:  greetingFor(day.Name),
+		})
+	}
+	return data, nil
+}
+
+// loadYear 读取某一年的缓存数据，没有缓存时在线拉取并写入缓存
+func loadYear(cfg DataConfig, year int) (*CalendarData, error) {
+	var cacheFile string
+	if cfg.CacheDir != "" {
+		cacheFile = filepath.Join(cfg.CacheDir, strconv.Itoa(year)+".json")
+		if b, err := os.ReadFile(cacheFile); err == nil {
+			return parseHolidayCN(b)
+		}
+	}
+
+	if cfg.FetchURL == "" {
+		return nil, fmt.Errorf("未配置在线数据地址")
+	}
+
+	b, err := fetchYear(strings.ReplaceAll(cfg.FetchURL, "{year}", strconv.Itoa(year)))
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseHolidayCN(b)
+	if err != nil {
+		return nil, err
+	}
+	if !data.HasYear(year) {
+		return nil, fmt.Errorf("在线数据年份不匹配: %v", data.Years)
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(cfg.CacheDir, 0755); err == nil {
+			os.WriteFile(cacheFile, b, 0644)
+		}
+	}
+	return data, nil
+}
+
+// fetchYear 下载在线数据
+func fetchYear(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求在线节假日数据失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("在线节假日数据返回状态码 %d（可能尚未发布）", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
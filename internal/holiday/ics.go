@@ -0,0 +1,557 @@
+package holiday
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 展开重复事件时的最大次数，防止无限循环
+const maxOccurrences = 5000
+
+// 从 ICS 日历导入的假期，ShouldSendReminder/ShouldSendOffWorkReminder 会一并检查
+// 配置热加载时在其他 goroutine 中替换，读写都要经过 importedMu
+var (
+	importedMu       sync.RWMutex
+	importedHolidays []Holiday
+)
+
+// ImportedHolidays 返回从 ICS 日历导入的假期，调用方不能修改
+func ImportedHolidays() []Holiday {
+	importedMu.RLock()
+	defer importedMu.RUnlock()
+	return importedHolidays
+}
+
+// icsEvent 解析后的 VEVENT
+type icsEvent struct {
+	Summary string
+	Start   time.Time // 开始日期（当天零点）
+	End     time.Time // 结束日期（不含）
+	Days    int       // 持续天数
+	RRule   map[string]string
+	ExDates map[string]bool
+
+	// 带时间的事件（DTSTART 不是 VALUE=DATE）只有覆盖整天时才算假期
+	timed    bool
+	startAt  time.Time
+	endAt    time.Time
+	duration time.Duration
+}
+
+// wholeDays 带时间的事件是否从零点开始、在零点结束，覆盖一天或多天
+func (e *icsEvent) wholeDays() bool {
+	end := e.endAt
+	if end.IsZero() {
+		end = e.startAt.Add(e.duration)
+	}
+	return e.startAt.Equal(startOfDay(e.startAt)) && end.After(e.startAt) && end.Equal(startOfDay(end))
+}
+
+// ImportICS 从本地文件或 URL 导入假期，重复事件展开到 [from, to] 范围内，替换之前导入的假期
+func ImportICS(sources []string, from, to time.Time) error {
+	var imported []Holiday
+	for _, source := range sources {
+		holidays, err := LoadICS(source, from, to)
+		if err != nil {
+			return fmt.Errorf("导入 %s 失败: %w", source, err)
+		}
+		imported = append(imported, holidays...)
+	}
+	importedMu.Lock()
+	importedHolidays = imported
+	importedMu.Unlock()
+	return nil
+}
+
+// LoadICS 读取本地文件或 URL 中的 ICS 日历
+func LoadICS(source string, from, to time.Time) ([]Holiday, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("返回状态码 %d", resp.StatusCode)
+		}
+		return ParseICS(resp.Body, from, to)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseICS(f, from, to)
+}
+
+// ParseICS 解析 ICS 日历中的 VEVENT 为假期，重复事件（RRULE）展开到 [from, to] 范围内
+// 全天事件和覆盖整天的事件算作假期，会议等只占一天中几个小时的事件忽略
+func ParseICS(r io.Reader, from, to time.Time) ([]Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var event *icsEvent
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icsEvent{Days: 1, ExDates: make(map[string]bool)}
+		case name == "END" && value == "VEVENT":
+			if event == nil || event.Start.IsZero() {
+				return nil, fmt.Errorf("VEVENT 缺少 DTSTART")
+			}
+			if event.timed && !event.wholeDays() {
+				logrus.Debugf("跳过带时间的日程 %s（%s）", event.Summary, event.startAt.Format("2006-01-02 15:04"))
+				event = nil
+				continue
+			}
+			if !event.End.IsZero() {
+				if days := int(event.End.Sub(event.Start).Hours()/24 + 0.5); days > 0 {
+					event.Days = days
+				}
+			}
+			occurrences, err := event.expand(from, to)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", event.Summary, err)
+			}
+			holidays = append(holidays, occurrences...)
+			event = nil
+		case event == nil:
+			continue
+		case name == "SUMMARY":
+			event.Summary = unescapeICSText(value)
+		case name == "DTSTART":
+			start, allDay, err := parseICSTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("DTSTART 无效: %w", err)
+			}
+			event.Start, event.startAt, event.timed = startOfDay(start), start, !allDay
+		case name == "DTEND":
+			end, at, err := parseICSEnd(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("DTEND 无效: %w", err)
+			}
+			event.End, event.endAt = end, at
+		case name == "DURATION":
+			event.duration = parseICSDuration(value)
+			if days := int((event.duration + 24*time.Hour - 1) / (24 * time.Hour)); days > 0 {
+				event.Days = days
+			}
+		case name == "RRULE":
+			event.RRule = parseRRule(value)
+		case name == "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				if d, err := parseICSDate(v, params); err == nil {
+					event.ExDates[d.Format("2006-01-02")] = true
+				}
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// unfoldICS 读取内容行并合并折叠行（以空格或制表符开头的行是上一行的续行）
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSLine 拆分 "NAME;PARAM=VALUE:value" 格式的内容行
+func splitICSLine(line string) (string, map[string]string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := make(map[string]string)
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseICSTime 解析 DATE（20250101）或 DATE-TIME（20250101T090000[Z]）
+func parseICSTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t.In(location), false, err
+	}
+
+	loc := location
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t.In(location), false, err
+}
+
+// parseICSDate 解析开始时间，返回所在日期的零点
+func parseICSDate(value string, params map[string]string) (time.Time, error) {
+	t, _, err := parseICSTime(value, params)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return startOfDay(t), nil
+}
+
+// parseICSEnd 解析结束时间（不含），返回结束日期次日的零点和原始的结束时间
+func parseICSEnd(value string, params map[string]string) (time.Time, time.Time, error) {
+	t, allDay, err := parseICSTime(value, params)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if allDay {
+		return t, t, nil
+	}
+	// 带时间的结束点：结束于当天零点则不包含当天
+	return startOfDay(t.Add(-time.Nanosecond)).AddDate(0, 0, 1), t, nil
+}
+
+// parseICSDuration 解析 DURATION（如 P1D、P2W、PT36H、PT1H30M）
+func parseICSDuration(value string) time.Duration {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	var d time.Duration
+	num := ""
+	inTime := false
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+			inTime = true
+		default:
+			n, _ := strconv.Atoi(num)
+			num = ""
+			switch {
+			case c == 'W':
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case c == 'D':
+				d += time.Duration(n) * 24 * time.Hour
+			case c == 'H' && inTime:
+				d += time.Duration(n) * time.Hour
+			case c == 'M' && inTime:
+				d += time.Duration(n) * time.Minute
+			case c == 'S' && inTime:
+				d += time.Duration(n) * time.Second
+			}
+		}
+	}
+	return d
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// unescapeICSText 还原 TEXT 类型中的转义字符
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// escapeICSText 转义 TEXT 类型中的特殊字符
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`).Replace(s)
+}
+
+// parseRRule 解析 "FREQ=WEEKLY;BYDAY=SA,SU;COUNT=10"
+func parseRRule(value string) map[string]string {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			rule[strings.ToUpper(kv[0])] = strings.ToUpper(kv[1])
+		}
+	}
+	return rule
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// expand 展开事件在 [from, to] 范围内的所有发生日期
+// 支持 FREQ=DAILY/WEEKLY/MONTHLY/YEARLY，以及 INTERVAL、COUNT、UNTIL、WEEKLY 的 BYDAY
+func (e *icsEvent) expand(from, to time.Time) ([]Holiday, error) {
+	var starts []time.Time
+	if e.RRule == nil {
+		starts = []time.Time{e.Start}
+	} else {
+		var err error
+		if starts, err = e.occurrences(to); err != nil {
+			return nil, err
+		}
+	}
+
+	var holidays []Holiday
+	for _, start := range starts {
+		if e.ExDates[start.Format("2006-01-02")] {
+			continue
+		}
+		end := start.AddDate(0, 0, e.Days-1)
+		if end.Before(startOfDay(from)) || start.After(to) {
+			continue
+		}
+		holidays = append(holidays, Holiday{
+			Name:      e.Summary,
+			StartDate: DateOf(start),
+			EndDate:   DateOf(end),
+		})
+	}
+	return holidays, nil
+}
+
+// occurrences 按 RRULE 计算各次开始日期，最晚到 to
+func (e *icsEvent) occurrences(to time.Time) ([]time.Time, error) {
+	interval := 1
+	if v, ok := e.RRule["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("RRULE INTERVAL 无效: %s", v)
+		}
+		interval = n
+	}
+
+	count := 0
+	if v, ok := e.RRule["COUNT"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("RRULE COUNT 无效: %s", v)
+		}
+		count = n
+	}
+
+	until := to
+	if v, ok := e.RRule["UNTIL"]; ok {
+		u, err := parseICSDate(v, nil)
+		if err != nil {
+			return nil, fmt.Errorf("RRULE UNTIL 无效: %s", v)
+		}
+		if u.Before(until) {
+			until = u
+		}
+	}
+
+	var byDay []time.Weekday
+	if v, ok := e.RRule["BYDAY"]; ok {
+		for _, d := range strings.Split(v, ",") {
+			// 忽略 "1MO" 这类序号前缀
+			d = strings.TrimLeft(d, "+-0123456789")
+			if wd, ok := icsWeekdays[d]; ok {
+				byDay = append(byDay, wd)
+			}
+		}
+	}
+
+	var starts []time.Time
+	add := func(t time.Time) bool {
+		if t.After(until) || (count > 0 && len(starts) >= count) || len(starts) >= maxOccurrences {
+			return false
+		}
+		if !t.Before(e.Start) {
+			starts = append(starts, t)
+		}
+		return true
+	}
+
+	switch e.RRule["FREQ"] {
+	case "DAILY":
+		for i := 0; add(e.Start.AddDate(0, 0, i*interval)); i++ {
+		}
+	case "WEEKLY":
+		if len(byDay) == 0 {
+			for i := 0; add(e.Start.AddDate(0, 0, 7*i*interval)); i++ {
+			}
+			break
+		}
+		sort.Slice(byDay, func(i, j int) bool { return byDay[i] < byDay[j] })
+		weekStart := e.Start.AddDate(0, 0, -int(e.Start.Weekday()))
+		for i := 0; len(starts) < maxOccurrences; i++ {
+			week := weekStart.AddDate(0, 0, 7*i*interval)
+			if week.After(until) {
+				break
+			}
+			for _, wd := range byDay {
+				if !add(week.AddDate(0, 0, int(wd))) {
+					return starts, nil
+				}
+			}
+		}
+	case "MONTHLY", "YEARLY":
+		for i := 0; len(starts) < maxOccurrences; i++ {
+			var t time.Time
+			if e.RRule["FREQ"] == "MONTHLY" {
+				t = e.Start.AddDate(0, i*interval, 0)
+			} else {
+				t = e.Start.AddDate(i*interval, 0, 0)
+			}
+			if t.After(until) {
+				break
+			}
+			// 跳过不存在的日期（如 2 月 30 日）
+			if t.Day() != e.Start.Day() {
+				continue
+			}
+			if !add(t) {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的 RRULE FREQ: %s", e.RRule["FREQ"])
+	}
+	return starts, nil
+}
+
+// WriteICS 把中国大陆日历（法定节假日、调休补班日）和自定义假期导出为 ICS，只包含与 [from, to] 有交集的条目
+func WriteICS(w io.Writer, custom []Holiday, from, to time.Time) error {
+	return Default().WriteICS(w, custom, from, to)
+}
+
+// WriteICS 把日历的节日、调休补班日、假期以及自定义假期导出为 ICS，只包含与 [from, to] 有交集的条目
+func (c *Calendar) WriteICS(w io.Writer, custom []Holiday, from, to time.Time) error {
+	fromDate, toDate := DateOf(from), DateOf(to)
+	overlaps := func(start, end Date) bool {
+		return !end.Before(fromDate) && !start.After(toDate)
+	}
+
+	var b strings.Builder
+	writeLine := func(line string) {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	writeEvent := func(kind, summary, description string, start, end Date) {
+		uid := fmt.Sprintf("%x", sha1.Sum([]byte(kind+start.String()+end.String()+summary)))
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + uid + "@weatherrobot")
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART;VALUE=DATE:" + start.Time().Format("20060102"))
+		writeLine("DTEND;VALUE=DATE:" + end.AddDays(1).Time().Format("20060102"))
+		writeLine("SUMMARY:" + escapeICSText(summary))
+		writeLine("DESCRIPTION:" + escapeICSText(description))
+		writeLine("CATEGORIES:" + kind)
+		writeLine("TRANSP:TRANSPARENT")
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//weatherrobot//holiday calendar//CN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:天气机器人日历（" + c.Name + "）")
+
+	for _, f := range c.Festivals {
+		if overlaps(f.StartDate, f.EndDate) {
+			writeEvent("FESTIVAL", f.Name, f.Greeting+"（机器人只发送节日问候，暂停天气报告和下班提醒）", f.StartDate, f.EndDate)
+		}
+	}
+	for _, d := range c.AdjustedWorkdays {
+		if overlaps(d.Date, d.Date) {
+			writeEvent("ADJUSTED-WORKDAY", d.Name+"调休补班", "调休补班日，机器人照常发送天气报告和下班提醒", d.Date, d.Date)
+		}
+	}
+	holidays := append(append(append([]Holiday{}, c.Holidays...), custom...), ImportedHolidays()...)
+	for _, h := range holidays {
+		if overlaps(h.StartDate, h.EndDate) {
+			writeEvent("HOLIDAY", h.Name, "自定义假期，机器人暂停天气报告和下班提醒", h.StartDate, h.EndDate)
+		}
+	}
+
+	writeLine("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// foldICSLine 按 RFC 5545 把超过 75 字节的内容行折叠，不拆分多字节字符
+func foldICSLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+// ICSHandler 返回导出日历的 HTTP 处理函数，参数 from/to（YYYY-MM-DD）默认为今年和明年，
+// 参数 calendar 为逗号分隔的日历名称，默认为中国大陆日历
+func ICSHandler(custom func() []Holiday) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().In(location)
+		from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, location)
+		to := time.Date(now.Year()+1, 12, 31, 0, 0, 0, 0, location)
+
+		for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+			if v := r.URL.Query().Get(key); v != "" {
+				d, err := ParseDate(v)
+				if err != nil {
+					http.Error(w, "invalid "+key+", want YYYY-MM-DD", http.StatusBadRequest)
+					return
+				}
+				*target = d.Time()
+			}
+		}
+
+		var names []string
+		if v := r.URL.Query().Get("calendar"); v != "" {
+			names = strings.Split(v, ",")
+		}
+		cal, err := Lookup(names)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="weatherrobot.ics"`)
+		cal.WriteICS(w, custom(), from, to)
+	}
+}
@@ -0,0 +1,138 @@
+package holiday
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:团建\r\n" +
+	"DTSTART;VALUE=DATE:20250303\r\n" +
+	"DTEND;VALUE=DATE:20250305\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:公司周年\r\n" +
+	" 庆\r\n" +
+	"DTSTART;VALUE=DATE:20240618\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:每周五居家办公\r\n" +
+	"DTSTART;VALUE=DATE:20250103\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=FR;COUNT=3\r\n" +
+	"EXDATE;VALUE=DATE:20250110\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:每周五读书会\r\n" +
+	"DTSTART;TZID=Asia/Shanghai:20250103T140000\r\n" +
+	"DTEND;TZID=Asia/Shanghai:20250103T170000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=FR;COUNT=3\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:年会\r\n" +
+	"DTSTART;TZID=Asia/Shanghai:20250124T000000\r\n" +
+	"DURATION:P1D\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2026, 12, 31, 0, 0, 0, 0, time.Local)
+
+	holidays, err := ParseICS(strings.NewReader(sampleICS), from, to)
+	if err != nil {
+		t.Fatalf("ParseICS error: %v", err)
+	}
+
+	want := []Holiday{
+		{Name: "团建", StartDate: mustDate("2025-03-03"), EndDate: mustDate("2025-03-04")},
+		{Name: "公司周年庆", StartDate: mustDate("2025-06-18"), EndDate: mustDate("2025-06-18")},
+		{Name: "公司周年庆", StartDate: mustDate("2026-06-18"), EndDate: mustDate("2026-06-18")},
+		{Name: "每周五居家办公", StartDate: mustDate("2025-01-03"), EndDate: mustDate("2025-01-03")},
+		{Name: "每周五居家办公", StartDate: mustDate("2025-01-17"), EndDate: mustDate("2025-01-17")},
+		{Name: "年会", StartDate: mustDate("2025-01-24"), EndDate: mustDate("2025-01-24")},
+	}
+	if len(holidays) != len(want) {
+		t.Fatalf("got %d holidays %v, want %d", len(holidays), holidays, len(want))
+	}
+	for i := range want {
+		if holidays[i] != want[i] {
+			t.Errorf("holidays[%d] = %+v, want %+v", i, holidays[i], want[i])
+		}
+	}
+}
+
+func TestTimedICSEventDoesNotSuppressReport(t *testing.T) {
+	const meeting = "BEGIN:VEVENT\r\n" +
+		"SUMMARY:季度复盘会\r\n" +
+		"DTSTART;TZID=Asia/Shanghai:20251015T140000\r\n" +
+		"DTEND;TZID=Asia/Shanghai:20251015T170000\r\n" +
+		"END:VEVENT\r\n"
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, Location())
+	holidays, err := ParseICS(strings.NewReader(meeting), from, from.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holidays) != 0 {
+		t.Errorf("3-hour event imported as holidays: %+v", holidays)
+	}
+
+	day := FixedClock(time.Date(2025, 10, 15, 8, 0, 0, 0, Location()))
+	if send, _, _ := ShouldSendReminder(day, holidays); !send {
+		t.Error("3-hour event suppressed the daily report")
+	}
+	if send, _, _ := ShouldSendOffWorkReminder(day, holidays); !send {
+		t.Error("3-hour event suppressed the off-work reminder")
+	}
+}
+
+func TestParseICSMissingStart(t *testing.T) {
+	_, err := ParseICS(strings.NewReader("BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\n"), time.Now(), time.Now())
+	if err == nil {
+		t.Fatal("expected error for VEVENT without DTSTART")
+	}
+}
+
+func TestWriteICSRoundTrip(t *testing.T) {
+	custom := []Holiday{{Name: "年假", StartDate: mustDate("2026-03-02"), EndDate: mustDate("2026-03-04")}}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2026, 12, 31, 0, 0, 0, 0, time.Local)
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, custom, from, to); err != nil {
+		t.Fatalf("WriteICS error: %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	events, err := ParseICS(&buf, from, to)
+	if err != nil {
+		t.Fatalf("ParseICS error: %v", err)
+	}
+
+	var foundCustom, foundSpring, foundAdjusted bool
+	for _, e := range events {
+		switch {
+		case e.Name == "年假" && e.StartDate.String() == "2026-03-02" && e.EndDate.String() == "2026-03-04":
+			foundCustom = true
+		case e.Name == "春节" && e.StartDate.String() == "2026-02-15" && e.EndDate.String() == "2026-02-23":
+			foundSpring = true
+		case e.Name == "春节调休补班" && e.StartDate.String() == "2026-02-14":
+			foundAdjusted = true
+		}
+		if e.StartDate.Year != 2026 {
+			t.Errorf("event outside range: %+v", e)
+		}
+	}
+	if !foundCustom || !foundSpring || !foundAdjusted {
+		t.Errorf("custom=%v spring=%v adjusted=%v, want all true", foundCustom, foundSpring, foundAdjusted)
+	}
+}
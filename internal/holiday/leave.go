@@ -0,0 +1,294 @@
+package holiday
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请假来源
+const (
+	LeaveFromConfig = "config" // 配置文件
+	LeaveFromChat   = "chat"   // 群聊 "/请假" 命令
+	LeaveFromICS    = "ics"    // 请假 ICS 日历
+)
+
+// Leave 个人请假，请假期间定时消息不再 @ 该用户
+type Leave struct {
+	User      string `mapstructure:"user" json:"user"`             // 企业微信 userid，与 mention_users 中一致
+	Name      string `mapstructure:"name" json:"name,omitempty"`   // 显示名称，为空时显示 userid
+	StartDate Date   `mapstructure:"start_date" json:"start_date"` // 开始日期 (YYYY-MM-DD)
+	EndDate   Date   `mapstructure:"end_date" json:"end_date"`     // 结束日期 (YYYY-MM-DD)
+	Source    string `mapstructure:"-" json:"source,omitempty"`    // 请假来源
+}
+
+// Contains 检查日期是否在请假期间（含首尾两天）
+func (l Leave) Contains(d Date) bool {
+	return !d.Before(l.StartDate) && !d.After(l.EndDate)
+}
+
+// DisplayName 返回显示名称
+func (l Leave) DisplayName() string {
+	if l.Name != "" {
+		return l.Name
+	}
+	return l.User
+}
+
+// LeaveICSSource 请假 ICS 日历
+type LeaveICSSource struct {
+	Source string `mapstructure:"source"` // 本地路径或 URL
+	User   string `mapstructure:"user"`   // 日历所属用户；为空时是共享日历，事件标题的第一个词为 userid
+}
+
+// LeaveConfig 请假配置
+type LeaveConfig struct {
+	Entries      []Leave          `mapstructure:"entries"`        // 固定的请假条目
+	File         string           `mapstructure:"file"`           // "/请假" 命令登记的请假（JSON Lines），为空则只保存在内存中
+	ICS          []LeaveICSSource `mapstructure:"ics"`            // 请假 ICS 日历
+	Team         []string         `mapstructure:"team"`           // 全部成员 userid，有人请假时用于把 "@all" 展开为具体成员
+	ShowInReport bool             `mapstructure:"show_in_report"` // 早报中附上 "今日请假" 一行
+}
+
+// ValidateLeaves 校验请假配置，返回所有无效条目的问题
+func ValidateLeaves(leaves []Leave) error {
+	var errs FieldErrors
+	for i, l := range leaves {
+		path := fmt.Sprintf("leave.entries[%d]", i)
+		switch {
+		case l.User == "":
+			errs.add(path+".user", "缺少 user")
+		case l.StartDate.IsZero():
+			errs.add(path+".start_date", "%s", named(l.User, "缺少开始日期"))
+		case l.EndDate.IsZero():
+			errs.add(path+".end_date", "%s", named(l.User, "缺少结束日期"))
+		case l.EndDate.Before(l.StartDate):
+			errs.add(path+".end_date", "%s", named(l.User, fmt.Sprintf("结束日期 %s 早于开始日期 %s", l.EndDate, l.StartDate)))
+		}
+	}
+	return errs.err()
+}
+
+// 请假登记会被群聊命令和定时任务并发访问
+var (
+	leaveMu        sync.RWMutex
+	leaveFile      string
+	configLeaves   []Leave
+	chatLeaves     []Leave
+	importedLeaves []Leave
+)
+
+// InitLeaves 加载配置中的请假条目和 "/请假" 命令登记的请假
+func InitLeaves(cfg LeaveConfig) error {
+	if err := ValidateLeaves(cfg.Entries); err != nil {
+		return err
+	}
+
+	entries := make([]Leave, len(cfg.Entries))
+	for i, l := range cfg.Entries {
+		l.Source = LeaveFromConfig
+		entries[i] = l
+	}
+
+	var chat []Leave
+	if cfg.File != "" {
+		var err error
+		if chat, err = readLeaveFile(cfg.File); err != nil {
+			return err
+		}
+	}
+
+	leaveMu.Lock()
+	defer leaveMu.Unlock()
+	configLeaves = entries
+	chatLeaves = chat
+	leaveFile = cfg.File
+	return nil
+}
+
+// readLeaveFile 读取请假记录，文件不存在时返回空
+func readLeaveFile(file string) ([]Leave, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取请假记录失败: %w", err)
+	}
+	defer f.Close()
+
+	var leaves []Leave
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var l Leave
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行无效: %w", file, line, err)
+		}
+		l.Source = LeaveFromChat
+		leaves = append(leaves, l)
+	}
+	return leaves, scanner.Err()
+}
+
+// writeLeaveFile 重写请假记录
+func writeLeaveFile(file string, leaves []Leave) error {
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	for _, l := range leaves {
+		line, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// AddLeave 登记 "/请假" 命令提交的请假，配置了 leave.file 时持久化
+func AddLeave(l Leave) error {
+	if err := ValidateLeaves([]Leave{l}); err != nil {
+		return err
+	}
+	l.Source = LeaveFromChat
+
+	leaveMu.Lock()
+	defer leaveMu.Unlock()
+	leaves := append(append([]Leave{}, chatLeaves...), l)
+	if leaveFile != "" {
+		if err := writeLeaveFile(leaveFile, leaves); err != nil {
+			return fmt.Errorf("保存请假记录失败: %w", err)
+		}
+	}
+	chatLeaves = leaves
+	return nil
+}
+
+// CancelLeaves 撤销用户通过 "/请假" 登记、尚未结束的请假，返回撤销的条目
+func CancelLeaves(user string, today Date) ([]Leave, error) {
+	leaveMu.Lock()
+	defer leaveMu.Unlock()
+
+	var kept, cancelled []Leave
+	for _, l := range chatLeaves {
+		if l.User == user && !l.EndDate.Before(today) {
+			cancelled = append(cancelled, l)
+			continue
+		}
+		kept = append(kept, l)
+	}
+	if len(cancelled) == 0 {
+		return nil, nil
+	}
+	if leaveFile != "" {
+		if err := writeLeaveFile(leaveFile, kept); err != nil {
+			return nil, fmt.Errorf("保存请假记录失败: %w", err)
+		}
+	}
+	chatLeaves = kept
+	return cancelled, nil
+}
+
+// ImportLeaveICS 从 ICS 日历导入请假，重复事件展开到 [from, to] 范围内
+func ImportLeaveICS(sources []LeaveICSSource, from, to time.Time) error {
+	var imported []Leave
+	for _, source := range sources {
+		events, err := LoadICS(source.Source, from, to)
+		if err != nil {
+			return fmt.Errorf("导入 %s 失败: %w", source.Source, err)
+		}
+		for _, e := range events {
+			user, name := source.User, e.Name
+			if user == "" {
+				// 共享日历：事件标题形如 "zhangsan 年假"
+				fields := strings.Fields(e.Name)
+				if len(fields) == 0 {
+					continue
+				}
+				user, name = fields[0], ""
+			}
+			imported = append(imported, Leave{User: user, Name: name, StartDate: e.StartDate, EndDate: e.EndDate, Source: LeaveFromICS})
+		}
+	}
+
+	leaveMu.Lock()
+	importedLeaves = imported
+	leaveMu.Unlock()
+	return nil
+}
+
+// Leaves 返回所有来源的请假条目
+func Leaves() []Leave {
+	leaveMu.RLock()
+	defer leaveMu.RUnlock()
+	all := make([]Leave, 0, len(configLeaves)+len(chatLeaves)+len(importedLeaves))
+	all = append(all, configLeaves...)
+	all = append(all, chatLeaves...)
+	return append(all, importedLeaves...)
+}
+
+// OnLeave 返回 t 当天请假的人，每人只返回一条
+func OnLeave(t time.Time) []Leave {
+	date := DateOf(t)
+	seen := make(map[string]bool)
+	var leaves []Leave
+	for _, l := range Leaves() {
+		if l.Contains(date) && !seen[l.User] {
+			seen[l.User] = true
+			leaves = append(leaves, l)
+		}
+	}
+	return leaves
+}
+
+// FilterMentions 去掉 t 当天请假的人
+// "@all" 无法排除个别成员，有人请假且配置了 team 时展开为 team 中未请假的成员，否则保留 "@all"
+func FilterMentions(t time.Time, mentions, team []string) []string {
+	away := make(map[string]bool)
+	for _, l := range OnLeave(t) {
+		away[l.User] = true
+	}
+	if len(away) == 0 {
+		return mentions
+	}
+
+	var filtered []string
+	seen := make(map[string]bool)
+	add := func(user string) {
+		if !away[user] && !seen[user] {
+			seen[user] = true
+			filtered = append(filtered, user)
+		}
+	}
+	for _, m := range mentions {
+		if m == "@all" {
+			if len(team) == 0 {
+				add(m)
+				continue
+			}
+			for _, user := range team {
+				add(user)
+			}
+			continue
+		}
+		add(m)
+	}
+	return filtered
+}
@@ -0,0 +1,65 @@
+package holiday
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterMentions(t *testing.T) {
+	err := InitLeaves(LeaveConfig{Entries: []Leave{
+		{User: "alice", StartDate: mustDate("2026-10-20"), EndDate: mustDate("2026-10-25")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer InitLeaves(LeaveConfig{})
+
+	onLeave := time.Date(2026, 10, 21, 8, 0, 0, 0, Location())
+	working := time.Date(2026, 10, 26, 8, 0, 0, 0, Location())
+	team := []string{"alice", "bob", "carol"}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		mentions []string
+		team     []string
+		want     []string
+	}{
+		{"explicit list", onLeave, []string{"alice", "bob"}, nil, []string{"bob"}},
+		{"@all expanded to team", onLeave, []string{"@all"}, team, []string{"bob", "carol"}},
+		{"@all without team", onLeave, []string{"@all"}, nil, []string{"@all"}},
+		{"nobody on leave", working, []string{"@all"}, team, []string{"@all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterMentions(tt.now, tt.mentions, tt.team); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterMentions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportLeaveICS(t *testing.T) {
+	const shared = "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:bob 年假\nDTSTART;VALUE=DATE:20261102\nDTEND;VALUE=DATE:20261104\nEND:VEVENT\nEND:VCALENDAR\n"
+	file := filepath.Join(t.TempDir(), "leave.ics")
+	if err := os.WriteFile(file, []byte(shared), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer ImportLeaveICS(nil, time.Time{}, time.Time{})
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, Location())
+	if err := ImportLeaveICS([]LeaveICSSource{{Source: file}, {Source: file, User: "carol"}}, from, from.AddDate(1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	leaves := OnLeave(time.Date(2026, 11, 3, 8, 0, 0, 0, Location()))
+	if len(leaves) != 2 || leaves[0].User != "bob" || leaves[1].User != "carol" || leaves[1].DisplayName() != "bob 年假" {
+		t.Errorf("OnLeave = %+v", leaves)
+	}
+	if len(OnLeave(time.Date(2026, 11, 4, 8, 0, 0, 0, Location()))) != 0 {
+		t.Error("DTEND is exclusive")
+	}
+}
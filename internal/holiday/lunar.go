@@ -0,0 +1,223 @@
+package holiday
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// 农历按天文算法推算：每月从朔日开始，含冬至的月为十一月，
+// 两个十一月之间有 13 个月时，第一个不含中气的月为闰月
+
+// LunarDate 农历日期
+type LunarDate struct {
+	Year  int  // 农历年（正月初一所在的公历年）
+	Month int  // 月，1-12
+	Day   int  // 日，1-30
+	Leap  bool // 是否为闰月
+}
+
+var (
+	lunarMonthNames = []string{"正", "二", "三", "四", "五", "六", "七", "八", "九", "十", "冬", "腊"}
+	lunarDayNames   = []string{
+		"初一", "初二", "初三", "初四", "初五", "初六", "初七", "初八", "初九", "初十",
+		"十一", "十二", "十三", "十四", "十五", "十六", "十七", "十八", "十九", "二十",
+		"廿一", "廿二", "廿三", "廿四", "廿五", "廿六", "廿七", "廿八", "廿九", "三十",
+	}
+)
+
+// String 返回 "闰六月初一" 格式
+func (l LunarDate) String() string {
+	leap := ""
+	if l.Leap {
+		leap = "闰"
+	}
+	return fmt.Sprintf("%s%s月%s", leap, lunarMonthNames[l.Month-1], lunarDayNames[l.Day-1])
+}
+
+// lunarMonth 农历月
+type lunarMonth struct {
+	Start Date // 朔日
+	Month int
+	Leap  bool
+	Year  int // 所属农历年
+}
+
+// newMoonDate 返回第 k 次朔的北京时间日期
+func newMoonDate(k float64) Date {
+	t := timeOfJulianDay(newMoon(k)).In(beijing)
+	return Date{t.Year(), t.Month(), t.Day()}
+}
+
+// newMoonIndexOnOrBefore 返回朔日不晚于 d 的最后一次朔的序号
+func newMoonIndexOnOrBefore(d Date) float64 {
+	jd := julianDay(time.Date(d.Year, d.Month, d.Day, 12, 0, 0, 0, time.UTC))
+	k := math.Floor((jd - 2451550.09766) / synodicMonth)
+	for newMoonDate(k).After(d) {
+		k--
+	}
+	for !newMoonDate(k + 1).After(d) {
+		k++
+	}
+	return k
+}
+
+// winterSolstice 返回某一年冬至的北京时间日期
+func winterSolstice(year int) Date {
+	t := solarTermTime(year, 23)
+	return Date{t.Year(), t.Month(), t.Day()}
+}
+
+// suiMonths 返回从 year-1 年冬至所在月（十一月）开始、到 year 年冬至所在月之前的农历月
+func suiMonths(year int) []lunarMonth {
+	start := newMoonIndexOnOrBefore(winterSolstice(year - 1))
+	end := newMoonIndexOnOrBefore(winterSolstice(year))
+	n := int(end - start)
+
+	starts := make([]Date, n+1)
+	for i := 0; i <= n; i++ {
+		starts[i] = newMoonDate(start + float64(i))
+	}
+
+	// 13 个月的岁中，第一个不含中气的月为闰月
+	leapIndex := -1
+	if n == 13 {
+		var zhongqi []Date
+		for _, y := range []int{year - 1, year} {
+			for i := 1; i < len(SolarTermNames); i += 2 {
+				t := solarTermTime(y, i)
+				zhongqi = append(zhongqi, Date{t.Year(), t.Month(), t.Day()})
+			}
+		}
+		for i := 1; i < n && leapIndex < 0; i++ {
+			hasZhongqi := false
+			for _, z := range zhongqi {
+				if !z.Before(starts[i]) && z.Before(starts[i+1]) {
+					hasZhongqi = true
+					break
+				}
+			}
+			if !hasZhongqi {
+				leapIndex = i
+			}
+		}
+	}
+
+	months := make([]lunarMonth, n)
+	month, lunarYear := 11, year-1
+	for i := 0; i < n; i++ {
+		leap := i == leapIndex
+		if i > 0 && !leap {
+			month = month%12 + 1
+			if month == 1 {
+				lunarYear = year
+			}
+		}
+		months[i] = lunarMonth{Start: starts[i], Month: month, Leap: leap, Year: lunarYear}
+	}
+	return months
+}
+
+// ToLunar 把公历日期转换为农历
+func ToLunar(d Date) LunarDate {
+	months := suiMonths(d.Year)
+	if next := suiMonths(d.Year + 1); !d.Before(next[0].Start) {
+		months = next
+	}
+	for i := len(months) - 1; i >= 0; i-- {
+		if !d.Before(months[i].Start) {
+			m := months[i]
+			return LunarDate{Year: m.Year, Month: m.Month, Day: d.Sub(m.Start) + 1, Leap: m.Leap}
+		}
+	}
+	// d 早于本岁十一月，属于上一岁
+	prev := suiMonths(d.Year - 1)
+	m := prev[len(prev)-1]
+	return LunarDate{Year: m.Year, Month: m.Month, Day: d.Sub(m.Start) + 1, Leap: m.Leap}
+}
+
+// lunarYearMonths 返回农历某一年的所有月（正月到腊月，含闰月）及下一年正月
+func lunarYearMonths(year int) []lunarMonth {
+	var months []lunarMonth
+	for _, m := range append(suiMonths(year), suiMonths(year+1)...) {
+		if m.Year == year || (m.Year == year+1 && m.Month == 1 && !m.Leap) {
+			months = append(months, m)
+		}
+	}
+	return months
+}
+
+// FromLunar 把农历日期转换为公历，该月没有这一天（如小月三十）时返回错误
+func FromLunar(l LunarDate) (Date, error) {
+	months := lunarYearMonths(l.Year)
+	for i := 0; i+1 < len(months); i++ {
+		m := months[i]
+		if m.Month != l.Month || m.Leap != l.Leap {
+			continue
+		}
+		length := months[i+1].Start.Sub(m.Start)
+		if l.Day < 1 || l.Day > length {
+			return Date{}, fmt.Errorf("农历%d年%s不存在（该月只有 %d 天）", l.Year, l, length)
+		}
+		return m.Start.AddDays(l.Day - 1), nil
+	}
+	return Date{}, fmt.Errorf("农历%d年没有%s", l.Year, l)
+}
+
+// LunarNewYearsEve 返回农历某一年的除夕（腊月最后一天）
+func LunarNewYearsEve(year int) Date {
+	next, _ := FromLunar(LunarDate{Year: year + 1, Month: 1, Day: 1})
+	return next.AddDays(-1)
+}
+
+// LeapMonth 返回农历某一年的闰月，没有闰月时返回 0
+func LeapMonth(year int) int {
+	for _, m := range lunarYearMonths(year) {
+		if m.Leap && m.Year == year {
+			return m.Month
+		}
+	}
+	return 0
+}
+
+// parseLunarDate 解析 "正月初一"、"八月十五"、"闰六月初一"、"腊月廿三" 等
+func parseLunarDate(s string) (month, day int, leap bool, err error) {
+	rest := strings.TrimPrefix(s, "闰")
+	leap = rest != s
+
+	monthPart, dayPart, ok := cut(rest, "月")
+	if !ok {
+		return 0, 0, false, fmt.Errorf("农历日期 %q 无效", s)
+	}
+	for i, name := range lunarMonthNames {
+		if monthPart == name {
+			month = i + 1
+		}
+	}
+	switch monthPart {
+	case "一":
+		month = 1
+	case "十一":
+		month = 11
+	case "十二":
+		month = 12
+	}
+	for i, name := range lunarDayNames {
+		if dayPart == name {
+			day = i + 1
+		}
+	}
+	if month == 0 || day == 0 {
+		return 0, 0, false, fmt.Errorf("农历日期 %q 无效", s)
+	}
+	return month, day, leap, nil
+}
+
+// cut 在第一个 sep 处切分字符串
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,86 @@
+package holiday
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFestivalRules(t *testing.T) {
+	// 国务院放假通知和紫金山天文台公布的日期
+	want := map[string]struct {
+		rule  string
+		dates []string
+	}{
+		"春节": {"农历正月初一", []string{"2024-02-10", "2025-01-29", "2026-02-17", "2027-02-06", "2028-01-26", "2029-02-13", "2030-02-03"}},
+		"除夕": {"除夕", []string{"2024-02-09", "2025-01-28", "2026-02-16", "2027-02-05", "2028-01-25", "2029-02-12", "2030-02-02"}},
+		"清明": {"清明", []string{"2024-04-04", "2025-04-04", "2026-04-05", "2027-04-05", "2028-04-04", "2029-04-04", "2030-04-05"}},
+		"端午": {"农历五月初五", []string{"2024-06-10", "2025-05-31", "2026-06-19", "2027-06-09", "2028-05-28", "2029-06-16", "2030-06-05"}},
+		"中秋": {"农历八月十五", []string{"2024-09-17", "2025-10-06", "2026-09-25", "2027-09-15", "2028-10-03", "2029-09-22", "2030-09-12"}},
+		"重阳": {"农历九月初九", []string{"2024-10-11", "2025-10-29", "2026-10-18", "2027-10-08", "2028-10-26", "2029-10-16", "2030-10-05"}},
+	}
+	for name, tt := range want {
+		rule := FestivalRule{Name: name, Date: tt.rule}
+		if err := rule.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		festivals := rule.FestivalsBetween(2024, 2030)
+		if len(festivals) != len(tt.dates) {
+			t.Fatalf("%s: got %d festivals, want %d", name, len(festivals), len(tt.dates))
+		}
+		for i, f := range festivals {
+			if f.StartDate.String() != tt.dates[i] || f.EndDate != f.StartDate {
+				t.Errorf("%s %d = %s..%s, want %s", name, 2024+i, f.StartDate, f.EndDate, tt.dates[i])
+			}
+		}
+	}
+}
+
+func TestToLunar(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+		year int
+	}{
+		{"2026-02-17", "正月初一", 2026},
+		{"2026-02-16", "腊月廿九", 2025},
+		{"2026-10-19", "九月初十", 2026},
+		{"2025-07-25", "闰六月初一", 2025},
+		{"2028-06-23", "闰五月初一", 2028},
+		{"2025-12-20", "冬月初一", 2025},
+	}
+	for _, tt := range tests {
+		got := ToLunar(mustDate(tt.date))
+		if got.String() != tt.want || got.Year != tt.year {
+			t.Errorf("ToLunar(%s) = %d %s, want %d %s", tt.date, got.Year, got, tt.year, tt.want)
+		}
+		back, err := FromLunar(got)
+		if err != nil || back.String() != tt.date {
+			t.Errorf("FromLunar(%d %s) = %s, %v, want %s", got.Year, got, back, err, tt.date)
+		}
+	}
+
+	for year, month := range map[int]int{2024: 0, 2025: 6, 2026: 0, 2028: 5} {
+		if got := LeapMonth(year); got != month {
+			t.Errorf("LeapMonth(%d) = %d, want %d", year, got, month)
+		}
+	}
+}
+
+func TestFestivalRuleErrors(t *testing.T) {
+	if _, err := FromLunar(LunarDate{Year: 2026, Month: 6, Day: 1, Leap: true}); err == nil {
+		t.Error("FromLunar(2026 闰六月) should fail: 2026 has no leap month")
+	}
+
+	rule := FestivalRule{Name: "小年", Date: "农历腊月廿三", Days: 2}
+	festivals := rule.FestivalsBetween(2026, 2026)
+	if len(festivals) != 1 || festivals[0].StartDate.String() != "2026-02-10" || festivals[0].EndDate.String() != "2026-02-11" {
+		t.Errorf("小年 2026 = %v, want 2026-02-10..2026-02-11", festivals)
+	}
+
+	for _, date := range []string{"农历十三月初一", "农历正月三十一", "立秋节", "13-01"} {
+		err := FestivalRule{Name: "test", Date: date}.Validate()
+		if err == nil || !strings.Contains(err.Error(), "无效") {
+			t.Errorf("Validate(%q) = %v, want invalid", date, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package holiday
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FestivalRule 按规则生成每年的节日，不必每年手工填写日期
+type FestivalRule struct {
+	Name     string `mapstructure:"name"`     // 节日名称
+	Date     string `mapstructure:"date"`     // 日期规则："农历正月初一"、"农历八月十五"、"除夕"、节气名（如 "清明"）或公历 "MM-DD"
+	Days     int    `mapstructure:"days"`     // 持续天数，默认 1 天
+	Greeting string `mapstructure:"greeting"` // 节日问候
+}
+
+// dateRule 解析后的日期规则，返回某一年（农历规则为农历年）对应的公历日期
+type dateRule func(year int) (Date, error)
+
+// parseDateRule 解析日期规则
+func parseDateRule(s string) (dateRule, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "除夕":
+		return func(year int) (Date, error) {
+			return LunarNewYearsEve(year), nil
+		}, nil
+	case strings.HasPrefix(s, "农历"):
+		month, day, leap, err := parseLunarDate(strings.TrimPrefix(s, "农历"))
+		if err != nil {
+			return nil, err
+		}
+		return func(year int) (Date, error) {
+			return FromLunar(LunarDate{Year: year, Month: month, Day: day, Leap: leap})
+		}, nil
+	}
+	if i, ok := solarTermIndex(s); ok {
+		return func(year int) (Date, error) {
+			t := solarTermTime(year, i)
+			return Date{t.Year(), t.Month(), t.Day()}, nil
+		}, nil
+	}
+	month, day, err := parseMonthDay(s)
+	if err != nil {
+		return nil, fmt.Errorf("日期规则 %q 无效，应为农历日期（如 \"农历正月初一\"）、\"除夕\"、节气名或 MM-DD", s)
+	}
+	return func(year int) (Date, error) {
+		if month == time.February && day == 29 && !isLeapYear(year) {
+			return Date{}, fmt.Errorf("%d 年没有 2 月 29 日", year)
+		}
+		return Date{year, month, day}, nil
+	}, nil
+}
+
+// Validate 校验规则
+func (r FestivalRule) Validate() error {
+	return r.errors().err()
+}
+
+func (r FestivalRule) errors() FieldErrors {
+	var errs FieldErrors
+	if r.Name == "" {
+		errs.add("name", "缺少 name")
+	}
+	if r.Days < 0 {
+		errs.add("days", "%s", named(r.Name, "days 不能为负数"))
+	}
+	if _, err := parseDateRule(r.Date); err != nil {
+		errs.add("date", "%s", named(r.Name, err.Error()))
+	}
+	return errs
+}
+
+// FestivalIn 返回规则在某一年生成的节日，农历规则的 year 为农历年
+func (r FestivalRule) FestivalIn(year int) (Festival, error) {
+	rule, err := parseDateRule(r.Date)
+	if err != nil {
+		return Festival{}, err
+	}
+	start, err := rule(year)
+	if err != nil {
+		return Festival{}, err
+	}
+	days := r.Days
+	if days == 0 {
+		days = 1
+	}
+	return Festival{Name: r.Name, StartDate: start, EndDate: start.AddDays(days - 1), Greeting: r.Greeting}, nil
+}
+
+// FestivalsBetween 返回规则在 [from, to] 年份范围内生成的节日
+// 农历年跨公历年（如腊月的节日落在次年 1 月），因此从 from-1 年开始生成再按公历年份筛选；
+// 某一年不存在的日期（如小月的三十）跳过
+func (r FestivalRule) FestivalsBetween(from, to int) []Festival {
+	var festivals []Festival
+	for year := from - 1; year <= to; year++ {
+		f, err := r.FestivalIn(year)
+		if err != nil {
+			continue
+		}
+		if f.StartDate.Year >= from && f.StartDate.Year <= to {
+			festivals = append(festivals, f)
+		}
+	}
+	return festivals
+}
@@ -0,0 +1,60 @@
+package holiday
+
+import "time"
+
+// 农历和节气按东八区（北京时间）确定日期，与配置的时区无关
+var beijing = time.FixedZone("CST", 8*3600)
+
+// SolarTermNames 二十四节气，从小寒（太阳视黄经 285°）开始，与公历一年中的顺序一致
+// 下标为奇数的是中气（大寒、雨水、春分……冬至），农历用它确定月份和闰月
+var SolarTermNames = []string{
+	"小寒", "大寒", "立春", "雨水", "惊蛰", "春分",
+	"清明", "谷雨", "立夏", "小满", "芒种", "夏至",
+	"小暑", "大暑", "立秋", "处暑", "白露", "秋分",
+	"寒露", "霜降", "立冬", "小雪", "大雪", "冬至",
+}
+
+// SolarTerm 节气及其交节时刻
+type SolarTerm struct {
+	Name string
+	Time time.Time // 交节时刻（北京时间）
+	Date Date      // 交节时刻所在的日期（北京时间）
+}
+
+// SolarTermsOf 按太阳视黄经计算某一年的二十四节气
+func SolarTermsOf(year int) []SolarTerm {
+	terms := make([]SolarTerm, len(SolarTermNames))
+	for i, name := range SolarTermNames {
+		t := solarTermTime(year, i)
+		terms[i] = SolarTerm{Name: name, Time: t, Date: Date{t.Year(), t.Month(), t.Day()}}
+	}
+	return terms
+}
+
+// solarTermTime 计算某一年第 i 个节气（从小寒开始）的交节时刻
+func solarTermTime(year, i int) time.Time {
+	// 小寒约在 1 月 5 日，之后每个节气相隔约 15.2 天
+	guess := julianDay(time.Date(year, 1, 5, 0, 0, 0, 0, time.UTC)) + 15.2184*float64(i)
+	target := normalizeDegrees(285 + 15*float64(i))
+	return timeOfJulianDay(solveSunLongitude(target, guess)).In(beijing)
+}
+
+// SolarTermOn 返回某一天交节的节气
+func SolarTermOn(d Date) (*SolarTerm, bool) {
+	for _, term := range SolarTermsOf(d.Year) {
+		if term.Date == d {
+			return &term, true
+		}
+	}
+	return nil, false
+}
+
+// solarTermIndex 返回节气名称的下标
+func solarTermIndex(name string) (int, bool) {
+	for i, n := range SolarTermNames {
+		if n == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
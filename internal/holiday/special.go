@@ -0,0 +1,254 @@
+package holiday
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// 特殊日子的类型
+const (
+	SpecialCountdown   = "countdown"   // 节日倒计时
+	SpecialSolarTerm   = "solar_term"  // 二十四节气
+	SpecialBirthday    = "birthday"    // 生日
+	SpecialAnniversary = "anniversary" // 入职周年
+)
+
+// 默认的问候模板
+const (
+	defaultCountdownTemplate   = "⏳ 距离{{.Name}}还有 {{.Days}} 天"
+	defaultSolarTermTemplate   = "🌿 今天{{.Time}}交节，二十四节气之「{{.Name}}」"
+	defaultBirthdayTemplate    = "🎂 {{if .Belated}}补祝{{.Name}}（{{.Date}}）{{else}}今天是{{.Name}}的生日，祝{{end}}生日快乐！"
+	defaultAnniversaryTemplate = "🎉 {{if .Belated}}{{.Date}}是{{else}}今天是{{end}}{{.Name}}入职 {{.Years}} 周年，感谢一路同行！"
+)
+
+// SpecialDayRule 一类特殊日子的开关、模板和发送方式
+type SpecialDayRule struct {
+	Enabled    bool   `mapstructure:"enabled"`    // 是否启用
+	Template   string `mapstructure:"template"`   // 问候模板（text/template），为空时使用默认模板
+	Standalone bool   `mapstructure:"standalone"` // 单独发送一条消息，否则附在天气报告中
+}
+
+// CountdownRule 节日倒计时
+type CountdownRule struct {
+	SpecialDayRule `mapstructure:",squash"`
+	MaxDays        int `mapstructure:"max_days"` // 距离节日不超过该天数时才显示倒计时，0 表示不限制
+}
+
+// Person 团队成员的生日和入职日期
+type Person struct {
+	User     string   `mapstructure:"user"`      // 企业微信 userid，单独发送时 @ 该用户
+	Name     string   `mapstructure:"name"`      // 显示名称
+	Birthday string   `mapstructure:"birthday"`  // 生日（MM-DD），为空表示不祝福
+	JoinDate Date     `mapstructure:"join_date"` // 入职日期，为空表示不祝福
+	Groups   []string `mapstructure:"groups"`    // 发送到哪些群，为空表示所有群
+}
+
+// InGroup 检查成员是否属于某个群
+func (p Person) InGroup(group string) bool {
+	if len(p.Groups) == 0 {
+		return true
+	}
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// SpecialDaysConfig 特殊日子配置
+type SpecialDaysConfig struct {
+	Countdown   CountdownRule  `mapstructure:"countdown"`
+	SolarTerm   SpecialDayRule `mapstructure:"solar_term"`
+	Birthday    SpecialDayRule `mapstructure:"birthday"`
+	Anniversary SpecialDayRule `mapstructure:"anniversary"`
+	People      []Person       `mapstructure:"people"`
+}
+
+// SpecialMessage 一条特殊日子的消息
+type SpecialMessage struct {
+	Kind       string
+	Text       string
+	Standalone bool
+	Mentions   []string // 单独发送时提及的用户
+}
+
+// specialData 模板可用的字段
+type specialData struct {
+	Name    string // 节日/节气/成员名称
+	Days    int    // 倒计时天数
+	Years   int    // 入职周年数
+	Date    string // 日期，如 "10月18日"
+	Time    string // 交节时刻，如 "14:29"
+	Belated bool   // 日子落在非工作日，在之后的第一个工作日补发
+}
+
+// ValidateSpecialDays 校验模板和成员配置，返回所有问题
+func ValidateSpecialDays(cfg SpecialDaysConfig) error {
+	var errs FieldErrors
+	for _, t := range []struct {
+		kind string
+		tmpl string
+	}{
+		{SpecialCountdown, cfg.Countdown.Template},
+		{SpecialSolarTerm, cfg.SolarTerm.Template},
+		{SpecialBirthday, cfg.Birthday.Template},
+		{SpecialAnniversary, cfg.Anniversary.Template},
+	} {
+		if t.tmpl == "" {
+			continue
+		}
+		if _, err := render(t.tmpl, specialData{}); err != nil {
+			errs.add("special_days."+t.kind+".template", "模板无效: %v", err)
+		}
+	}
+	for i, p := range cfg.People {
+		path := fmt.Sprintf("special_days.people[%d]", i)
+		if p.Name == "" {
+			errs.add(path+".name", "缺少 name")
+		}
+		if p.Birthday != "" {
+			if _, _, err := parseMonthDay(p.Birthday); err != nil {
+				errs.add(path+".birthday", "%s", named(p.Name, err.Error()))
+			}
+		}
+	}
+	return errs.err()
+}
+
+// parseMonthDay 解析 MM-DD，2 月 29 日合法
+func parseMonthDay(s string) (time.Month, int, error) {
+	t, err := time.Parse("2006-01-02", "2000-"+s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("日期 %q 无效，格式应为 MM-DD", s)
+	}
+	return t.Month(), t.Day(), nil
+}
+
+// render 渲染问候模板
+func render(tmpl string, data specialData) (string, error) {
+	t, err := template.New("special").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func templateOr(tmpl, fallback string) string {
+	if tmpl != "" {
+		return tmpl
+	}
+	return fallback
+}
+
+// SpecialDays 返回天气报告当天要发送的特殊日子消息
+// 生日和入职周年如果落在上一个工作日之后的非工作日（周末、节假日），在今天补发
+func (c *Calendar) SpecialDays(cfg SpecialDaysConfig, now time.Time, holidays []Holiday) ([]SpecialMessage, error) {
+	today := DateOf(now)
+	var messages []SpecialMessage
+	add := func(rule SpecialDayRule, kind, fallback string, data specialData, mentions []string) error {
+		text, err := render(templateOr(rule.Template, fallback), data)
+		if err != nil {
+			return fmt.Errorf("渲染 %s 模板失败: %w", kind, err)
+		}
+		msg := SpecialMessage{Kind: kind, Text: text, Standalone: rule.Standalone}
+		if rule.Standalone {
+			msg.Mentions = mentions
+		}
+		messages = append(messages, msg)
+		return nil
+	}
+
+	if cfg.Countdown.Enabled {
+		if f := c.NextFestival(today); f != nil {
+			days := f.StartDate.Sub(today)
+			if cfg.Countdown.MaxDays == 0 || days <= cfg.Countdown.MaxDays {
+				if err := add(cfg.Countdown.SpecialDayRule, SpecialCountdown, defaultCountdownTemplate, specialData{Name: f.Name, Days: days}, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if cfg.SolarTerm.Enabled {
+		if term, ok := SolarTermOn(today); ok {
+			data := specialData{Name: term.Name, Date: monthDay(today), Time: term.Time.Format("15:04")}
+			if err := add(cfg.SolarTerm, SpecialSolarTerm, defaultSolarTermTemplate, data, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 上一个工作日之后到今天的日子都要祝福
+	since := today
+	for d := 1; d <= maxGapDays*2; d++ {
+		day := today.AddDays(-d)
+		if c.IsWorkingDay(day.Time(), holidays) {
+			break
+		}
+		since = day
+	}
+
+	for _, p := range cfg.People {
+		var mentions []string
+		if p.User != "" {
+			mentions = []string{p.User}
+		}
+		for day := since; !day.After(today); day = day.AddDays(1) {
+			data := specialData{Name: p.Name, Date: monthDay(day), Belated: day != today}
+			if cfg.Birthday.Enabled && matchesMonthDay(p.Birthday, day) {
+				if err := add(cfg.Birthday, SpecialBirthday, defaultBirthdayTemplate, data, mentions); err != nil {
+					return nil, err
+				}
+			}
+			if cfg.Anniversary.Enabled && !p.JoinDate.IsZero() && matchesMonthDay(fmt.Sprintf("%02d-%02d", p.JoinDate.Month, p.JoinDate.Day), day) {
+				if data.Years = day.Year - p.JoinDate.Year; data.Years > 0 {
+					if err := add(cfg.Anniversary, SpecialAnniversary, defaultAnniversaryTemplate, data, mentions); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return messages, nil
+}
+
+// matchesMonthDay 检查 day 是否为 MM-DD 的周年日，2 月 29 日的生日在平年按 2 月 28 日算
+func matchesMonthDay(monthDay string, day Date) bool {
+	if monthDay == "" {
+		return false
+	}
+	month, dom, err := parseMonthDay(monthDay)
+	if err != nil {
+		return false
+	}
+	if month == time.February && dom == 29 && !isLeapYear(day.Year) {
+		return day.Month == time.February && day.Day == 28
+	}
+	return day.Month == month && day.Day == dom
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// monthDay 返回 "10月18日" 格式
+func monthDay(d Date) string {
+	return fmt.Sprintf("%d月%d日", d.Month, d.Day)
+}
+
+// NextFestival 返回今天之后最近开始的节日
+func (c *Calendar) NextFestival(today Date) *Festival {
+	for i, f := range c.Festivals {
+		if f.StartDate.After(today) {
+			return &c.Festivals[i]
+		}
+	}
+	return nil
+}
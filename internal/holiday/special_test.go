@@ -0,0 +1,83 @@
+package holiday
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolarTermsOf(t *testing.T) {
+	// 紫金山天文台公布的交节日期（北京时间）
+	want := map[string][]string{
+		"立春": {"2024-02-04", "2025-02-03", "2026-02-04"},
+		"清明": {"2024-04-04", "2025-04-04", "2026-04-05"},
+		"夏至": {"2024-06-21", "2025-06-21", "2026-06-21"},
+		"秋分": {"2024-09-22", "2025-09-23", "2026-09-23"},
+		"冬至": {"2024-12-21", "2025-12-21", "2026-12-22"},
+	}
+	for i, year := range []int{2024, 2025, 2026} {
+		terms := SolarTermsOf(year)
+		if len(terms) != 24 {
+			t.Fatalf("SolarTermsOf(%d) returned %d terms", year, len(terms))
+		}
+		for _, term := range terms {
+			if dates, ok := want[term.Name]; ok && term.Date.String() != dates[i] {
+				t.Errorf("%d %s = %s, want %s", year, term.Name, term.Date, dates[i])
+			}
+		}
+	}
+
+	if term, ok := SolarTermOn(mustDate("2026-10-23")); !ok || term.Name != "霜降" {
+		t.Errorf("SolarTermOn(2026-10-23) = %v, %v", term, ok)
+	}
+}
+
+func TestSpecialDays(t *testing.T) {
+	cfg := SpecialDaysConfig{
+		Countdown:   CountdownRule{SpecialDayRule: SpecialDayRule{Enabled: true}, MaxDays: 7},
+		SolarTerm:   SpecialDayRule{Enabled: true},
+		Birthday:    SpecialDayRule{Enabled: true, Standalone: true},
+		Anniversary: SpecialDayRule{Enabled: true, Template: "{{.Name}} {{.Years}} 周年"},
+		People: []Person{
+			{User: "alice", Name: "小艾", Birthday: "10-18", JoinDate: mustDate("2020-10-19")},
+			{User: "bob", Name: "阿宝", Birthday: "02-29"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		date string
+		want []string
+	}{
+		{"countdown", "2026-09-28", []string{"⏳ 距离国庆节还有 3 天"}},
+		{"countdown beyond max days", "2026-09-14", nil},
+		{"solar term", "2026-10-08", []string{"今天14:29交节，二十四节气之「寒露」"}},
+		{"belated birthday and anniversary", "2026-10-19", []string{"🎂 补祝小艾（10月18日）生日快乐！", "小艾 6 周年"}},
+		{"leap day birthday in common year", "2027-03-01", []string{"补祝阿宝（2月28日）"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := Default().SpecialDays(cfg, mustDate(tt.date).Time().Add(8*time.Hour), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != len(tt.want) {
+				t.Fatalf("got %d messages %+v, want %d", len(messages), messages, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(messages[i].Text, want) {
+					t.Errorf("message %q does not contain %q", messages[i].Text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSpecialDays(t *testing.T) {
+	if err := ValidateSpecialDays(SpecialDaysConfig{Birthday: SpecialDayRule{Template: "{{.Nmae"}}); err == nil {
+		t.Error("invalid template should fail")
+	}
+	if err := ValidateSpecialDays(SpecialDaysConfig{People: []Person{{Name: "x", Birthday: "02-30"}}}); err == nil {
+		t.Error("invalid birthday should fail")
+	}
+}
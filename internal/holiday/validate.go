@@ -0,0 +1,58 @@
+package holiday
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError 配置中某个字段的问题，Field 为配置路径，如 "holidays[0].end_date"
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// FieldErrors 校验发现的所有问题
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add 记录一个问题
+func (e *FieldErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge 合并另一组问题，字段路径加上前缀
+func (e *FieldErrors) merge(prefix string, other FieldErrors) {
+	for _, fe := range other {
+		if prefix != "" {
+			fe.Field = prefix + "." + fe.Field
+		}
+		*e = append(*e, fe)
+	}
+}
+
+// err 没有问题时返回 nil，避免返回非空接口包着的空切片
+func (e FieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// named 在消息后附上条目名称，便于定位
+func named(name, message string) string {
+	if name == "" {
+		return message
+	}
+	return message + "（" + name + "）"
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 日志字段名
+const (
+	FieldJob       = "job"        // 定时任务
+	FieldGroup     = "group"      // 接收定时消息的群
+	FieldLocation  = "location"   // 和风天气城市
+	FieldRequestID = "request_id" // 企业微信回调消息
+	FieldUser      = "user"       // 发消息的用户
+)
+
+type fieldsKey struct{}
+
+// WithField 返回附带日志字段的 context，From 取得的 logger 会带上 ctx 中的所有字段
+func WithField(ctx context.Context, key string, value interface{}) context.Context {
+	return WithFields(ctx, logrus.Fields{key: value})
+}
+
+// WithFields 返回附带多个日志字段的 context，与已有字段同名时覆盖
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	parent, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	merged := make(logrus.Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// From 返回带有 ctx 中日志字段的 logger
+func From(ctx context.Context) *logrus.Entry {
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	return logrus.WithFields(fields)
+}
@@ -1,22 +1,123 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-var Logger = logrus.New()
+// 日志格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config 日志配置
+type Config struct {
+	Level      string `mapstructure:"level"`        // debug、info、warn、error，默认 info；debug 时输出接口响应等完整内容
+	Format     string `mapstructure:"format"`       // text 或 json，默认 text
+	File       string `mapstructure:"file"`         // 日志文件，为空时输出到标准错误
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`  // 日志文件超过该大小（MB）时轮转，0 表示不轮转
+	MaxAgeDays int    `mapstructure:"max_age_days"` // 轮转后的旧文件保留天数，0 表示不按时间删除
+	MaxBackups int    `mapstructure:"max_backups"`  // 最多保留的旧文件数，0 表示不限制
+}
+
+// Validate 检查日志级别和格式
+func (c Config) Validate() error {
+	if _, err := parseLevel(c.Level); err != nil {
+		return err
+	}
+	if _, err := formatter(c.Format); err != nil {
+		return err
+	}
+	if c.MaxSizeMB < 0 || c.MaxAgeDays < 0 || c.MaxBackups < 0 {
+		return fmt.Errorf("max_size_mb、max_age_days 和 max_backups 不能为负数")
+	}
+	return nil
+}
+
+// 当前打开的日志文件，重新配置时关闭
+var (
+	mu   sync.Mutex
+	file io.Closer
+)
 
+// Init 初始化全局 logger：文本格式输出到标准错误，输出前隐藏密钥
+// 加载配置后调用 Configure 按配置设置级别、格式和日志文件
 func Init() {
-	Logger.Out = os.Stdout
-	Logger.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetOutput(os.Stderr)
+	logrus.SetLevel(logrus.InfoLevel)
+	logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	logrus.AddHook(redactHook{})
+}
+
+// Configure 按配置设置全局 logger，配置热加载时可以重复调用
+func Configure(cfg Config) error {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	f, err := formatter(cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer
+	if cfg.File != "" {
+		w, err := openRotateWriter(cfg.File, int64(cfg.MaxSizeMB)<<20, time.Duration(cfg.MaxAgeDays)*24*time.Hour, cfg.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		out, closer = w, w
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	logrus.SetOutput(out)
+	logrus.SetFormatter(f)
+	logrus.SetLevel(level)
+	if file != nil {
+		file.Close()
+	}
+	file = closer
+	return nil
+}
+
+// Close 关闭日志文件，之后的日志输出到标准错误
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	logrus.SetOutput(os.Stderr)
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
 }
 
-func Info(args ...interface{}) {
-	Logger.Info(args...)
+func parseLevel(level string) (logrus.Level, error) {
+	if level == "" {
+		return logrus.InfoLevel, nil
+	}
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return 0, fmt.Errorf("日志级别 %q 无效，可选 debug、info、warn、error", level)
+	}
+	return l, nil
 }
 
-func Error(args ...interface{}) {
-	Logger.Error(args...)
+func formatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", FormatText:
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("日志格式 %q 无效，可选 text、json", format)
 }
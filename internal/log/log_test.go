@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	if err := (Config{Level: "verbose"}).Validate(); err == nil || !strings.Contains(err.Error(), "verbose") {
+		t.Errorf("Validate(level verbose) = %v", err)
+	}
+	if err := (Config{Format: "xml"}).Validate(); err == nil {
+		t.Error("Validate(format xml) should fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "robot.log")
+	if err := Configure(Config{Level: "debug", Format: FormatJSON, File: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(Config{})
+	defer Close()
+
+	ctx := WithField(WithField(context.Background(), FieldJob, "daily_report"), FieldGroup, "研发群")
+	From(ctx).Debug("响应内容")
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`"job":"daily_report"`, `"group":"研发群"`, `"level":"debug"`, `"msg":"响应内容"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log %s does not contain %s", data, want)
+		}
+	}
+}
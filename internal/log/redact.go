@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "***"
+
+// 配置中的密钥，日志输出前替换为 ***
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// URL 中的 key 参数（企业微信 webhook、和风天气接口），即使不在密钥列表中也隐藏
+var keyParamPattern = regexp.MustCompile(`([?&]key=)[^&\s"']+`)
+
+// SetSecrets 设置需要在日志中隐藏的密钥，配置重新加载时整体替换
+func SetSecrets(values []string) {
+	sorted := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			sorted = append(sorted, v)
+		}
+	}
+	// 先替换长的，避免 webhook 地址被其中的 key 先替换掉一部分
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	secretsMu.Lock()
+	secrets = sorted
+	secretsMu.Unlock()
+}
+
+// Redact 隐藏字符串中的密钥
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.RUnlock()
+	return keyParamPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactHook 在日志输出前隐藏消息和字段中的密钥
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			entry.Data[k] = Redact(v)
+		case error:
+			entry.Data[k] = Redact(v.Error())
+		case fmt.Stringer:
+			entry.Data[k] = Redact(v.String())
+		}
+	}
+	return nil
+}
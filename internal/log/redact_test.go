@@ -0,0 +1,36 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedact(t *testing.T) {
+	webhook := "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=FAKE-WEBHOOK-KEY"
+	SetSecrets([]string{"FAKE-WEBHOOK-KEY", webhook, "sk-secret", ""})
+	defer SetSecrets(nil)
+
+	tests := map[string]string{
+		"Post " + webhook + ": timeout":                             "Post ***: timeout",
+		"调用 OpenAI 失败: invalid key sk-secret":                       "调用 OpenAI 失败: invalid key ***",
+		"https://api.qweather.com/v7/indices/1d?key=abc&location=1": "https://api.qweather.com/v7/indices/1d?key=***&location=1",
+	}
+	for in, want := range tests {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.AddHook(redactHook{})
+	logger.WithError(errors.New("bad key sk-secret")).Errorf("请求 %s 失败", webhook)
+	if out := buf.String(); strings.Contains(out, "sk-secret") || strings.Contains(out, "FAKE-WEBHOOK-KEY") {
+		t.Errorf("log output leaks secrets: %s", out)
+	}
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 轮转后旧文件名中的时间格式，按文件名排序即按时间排序
+const backupTimeFormat = "20060102-150405.000"
+
+// rotateWriter 写入日志文件，超过大小时把当前文件改名为 name-时间.ext 并新建文件，
+// 同时删除超过保留天数或数量的旧文件
+type rotateWriter struct {
+	path       string
+	maxSize    int64         // 0 表示不轮转
+	maxAge     time.Duration // 0 表示不按时间删除
+	maxBackups int           // 0 表示不限制
+	now        func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func openRotateWriter(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotateWriter, error) {
+	w := &rotateWriter{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups, now: time.Now}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.removeOld()
+	return w, nil
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size = f, info.Size()
+	return nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// rotate 把当前文件改名为带时间的旧文件，新建日志文件
+func (w *rotateWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+	prefix, ext := w.backupPattern()
+	if err := os.Rename(w.path, prefix+w.now().Format(backupTimeFormat)+ext); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.removeOld()
+	return nil
+}
+
+// backupPattern 返回旧文件名中时间前后的部分，如 logs/weatherrobot.log 对应 logs/weatherrobot- 和 .log
+func (w *rotateWriter) backupPattern() (prefix, ext string) {
+	ext = filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+// removeOld 删除超过保留天数或数量的旧文件，失败时忽略，下次轮转再试
+func (w *rotateWriter) removeOld() {
+	if w.maxAge <= 0 && w.maxBackups <= 0 {
+		return
+	}
+	prefix, ext := w.backupPattern()
+	matches, _ := filepath.Glob(prefix + "*" + ext)
+	// 文件名按时间排序，新的在前
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+
+	cutoff := w.now().Add(-w.maxAge)
+	kept := 0
+	for _, m := range matches {
+		rotated, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext), time.Local)
+		if err != nil {
+			continue // 不是轮转产生的文件
+		}
+		if w.maxBackups > 0 && kept >= w.maxBackups || w.maxAge > 0 && rotated.Before(cutoff) {
+			os.Remove(m)
+			continue
+		}
+		kept++
+	}
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "robot.log")
+	now := time.Now()
+
+	// 一个过期的旧文件和一个无关文件
+	os.MkdirAll(filepath.Join(dir, "logs"), 0755)
+	expired := filepath.Join(dir, "logs", "robot-"+now.AddDate(0, 0, -10).Format(backupTimeFormat)+".log")
+	other := filepath.Join(dir, "logs", "robot-notes.log")
+	os.WriteFile(expired, []byte("old\n"), 0644)
+	os.WriteFile(other, []byte("keep\n"), 0644)
+
+	w, err := openRotateWriter(path, 10, 7*24*time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.now = func() time.Time { return now }
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Errorf("expired backup was not removed")
+	}
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		if _, err := w.Write([]byte("line " + string(rune('a'+i)) + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "logs", "robot-2*.log"))
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want 2", backups)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "line d\n" {
+		t.Errorf("current file = %q", data)
+	}
+	if data, _ := os.ReadFile(backups[1]); string(data) != "line c\n" {
+		t.Errorf("newest backup = %q", data)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("unrelated file was removed: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weatherrobot"
+
+// 定时任务名，与 schedules 中的配置项一致，指标、执行记录和 LLM 用量统计都使用这些名称
+const (
+	JobDailyReport      = "daily_report"
+	JobOffWork          = "off_work"
+	JobRefreshCalendars = "refresh_calendars"
+)
+
+// 定时任务对一个群的执行结果
+const (
+	OutcomeSuccess = "success" // 已发送
+	OutcomePartial = "partial" // 已发送，但部分城市的天气获取失败
+	OutcomeSkipped = "skipped" // 按日历跳过，如节假日、周末
+	OutcomeFailed  = "failed"
+)
+
+var (
+	jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "定时任务对每个群的执行次数，按结果区分",
+	}, []string{"job", "outcome"})
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "定时任务执行耗时",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	}, []string{"job"})
+	jobLastRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_run_timestamp_seconds",
+		Help:      "定时任务最近一次执行完成的时间",
+	}, []string{"job"})
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "定时任务最近一次所有群都没有失败（已发送或按日历跳过）的时间",
+	}, []string{"job"})
+
+	qweatherDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "qweather_request_duration_seconds",
+		Help:      "和风天气接口请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	qweatherErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qweather_request_errors_total",
+		Help:      "和风天气接口请求失败次数（网络错误或返回码不是 200）",
+	}, []string{"endpoint"})
+
+	wecomSends = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wecom_send_total",
+		Help:      "企业微信群机器人发送次数，按 errcode 区分；请求失败时为 error，HTTP 状态异常时为 http_<状态码>",
+	}, []string{"errcode"})
+
+	llmDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "LLM 调用耗时",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"provider"})
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM 消耗的 token 数，type 为 prompt 或 completion",
+	}, []string{"provider", "type"})
+	llmFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_failures_total",
+		Help:      "LLM 调用失败次数",
+	}, []string{"provider"})
+
+	chatReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_messages_received_total",
+		Help:      "收到的 @机器人 提问数",
+	})
+	chatThrottled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_messages_throttled_total",
+		Help:      "被限流的提问数",
+	})
+	chatDuplicated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_messages_duplicated_total",
+		Help:      "因企业微信重复回调被丢弃的消息数",
+	})
+
+	configReloadFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reload_failures_total",
+		Help:      "配置文件修改后无效、继续使用原配置的次数",
+	})
+)
+
+// ObserveJob 记录一次定时任务执行，outcomes 为每个群的结果
+func ObserveJob(job string, start time.Time, outcomes ...string) {
+	failed := false
+	for _, o := range outcomes {
+		jobRuns.WithLabelValues(job, o).Inc()
+		failed = failed || o == OutcomeFailed || o == OutcomePartial
+	}
+	now := time.Now()
+	jobDuration.WithLabelValues(job).Observe(now.Sub(start).Seconds())
+	jobLastRun.WithLabelValues(job).Set(float64(now.Unix()))
+	if !failed {
+		jobLastSuccess.WithLabelValues(job).Set(float64(now.Unix()))
+	}
+}
+
+// ObserveQWeather 记录一次和风天气接口请求
+func ObserveQWeather(endpoint string, start time.Time, err error) {
+	qweatherDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		qweatherErrors.WithLabelValues(endpoint).Inc()
+	}
+}
+
+// ObserveWecomSend 记录一次企业微信发送结果
+func ObserveWecomSend(errcode string) {
+	wecomSends.WithLabelValues(errcode).Inc()
+}
+
+// ObserveWecomHTTPStatus 记录一次 HTTP 状态异常的企业微信发送
+func ObserveWecomHTTPStatus(status int) {
+	ObserveWecomSend("http_" + strconv.Itoa(status))
+}
+
+// ObserveLLM 记录一次 LLM 调用
+func ObserveLLM(provider string, latency time.Duration, promptTokens, completionTokens int, err error) {
+	llmDuration.WithLabelValues(provider).Observe(latency.Seconds())
+	llmTokens.WithLabelValues(provider, "prompt").Add(float64(promptTokens))
+	llmTokens.WithLabelValues(provider, "completion").Add(float64(completionTokens))
+	if err != nil {
+		llmFailures.WithLabelValues(provider).Inc()
+	}
+}
+
+// ChatReceived 记录收到一条 @机器人 提问
+func ChatReceived() { chatReceived.Inc() }
+
+// ChatThrottled 记录一条被限流的提问
+func ChatThrottled() { chatThrottled.Inc() }
+
+// ChatDuplicated 记录一条重复回调的消息
+func ChatDuplicated() { chatDuplicated.Inc() }
+
+// ConfigReloadFailed 记录一次被拒绝的配置热加载
+func ConfigReloadFailed() { configReloadFailures.Inc() }
+
+// Handler 返回 /metrics 的处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveJob(t *testing.T) {
+	start := time.Now()
+	ObserveJob("daily_report", start, OutcomeSuccess, OutcomeSkipped)
+	success := testutil.ToFloat64(jobLastSuccess.WithLabelValues("daily_report"))
+	if success == 0 {
+		t.Fatal("last success not set")
+	}
+	if got := testutil.ToFloat64(jobRuns.WithLabelValues("daily_report", OutcomeSkipped)); got != 1 {
+		t.Errorf("skipped runs = %v, want 1", got)
+	}
+
+	// 任一群失败时不更新最近成功时间
+	jobLastSuccess.WithLabelValues("daily_report").Set(1)
+	ObserveJob("daily_report", start, OutcomeSuccess, OutcomeFailed)
+	if got := testutil.ToFloat64(jobLastSuccess.WithLabelValues("daily_report")); got != 1 {
+		t.Errorf("last success updated after failure: %v", got)
+	}
+	if got := testutil.ToFloat64(jobLastRun.WithLabelValues("daily_report")); got < float64(start.Unix()) {
+		t.Errorf("last run = %v", got)
+	}
+}
+
+func TestObserveLLM(t *testing.T) {
+	ObserveLLM("Doubao", time.Second, 120, 30, nil)
+	ObserveLLM("Doubao", time.Second, 0, 0, errors.New("timeout"))
+	if got := testutil.ToFloat64(llmTokens.WithLabelValues("Doubao", "prompt")); got != 120 {
+		t.Errorf("prompt tokens = %v, want 120", got)
+	}
+	if got := testutil.ToFloat64(llmFailures.WithLabelValues("Doubao")); got != 1 {
+		t.Errorf("failures = %v, want 1", got)
+	}
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket 令牌桶限流器
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶容量
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket 创建令牌桶，perMinute 为每分钟补充的令牌数，burst 为桶容量
+// perMinute <= 0 表示不限流
+func NewBucket(perMinute, burst int) *Bucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Allow 尝试取出一个令牌
+func (b *Bucket) Allow() bool {
+	return b.AllowAt(time.Now())
+}
+
+// AllowAt 在指定时间尝试取出一个令牌
+func (b *Bucket) AllowAt(now time.Time) bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Refund 退回一个令牌，用于取出令牌后因其他限制没有使用的情况
+func (b *Bucket) Refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// full 检查桶是否已满（长时间未使用）
+func (b *Bucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// 按 key 限流时，超过该数量的桶会触发一次清理
+const pruneThreshold = 1024
+
+// KeyedLimiter 按 key（如用户 ID）分别限流
+type KeyedLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	burst     int
+	buckets   map[string]*Bucket
+}
+
+// NewKeyedLimiter 创建按 key 限流的限流器
+func NewKeyedLimiter(perMinute, burst int) *KeyedLimiter {
+	return &KeyedLimiter{
+		perMinute: perMinute,
+		burst:     burst,
+		buckets:   make(map[string]*Bucket),
+	}
+}
+
+// Allow 尝试为 key 取出一个令牌
+func (l *KeyedLimiter) Allow(key string) bool {
+	return l.AllowAt(key, time.Now())
+}
+
+// AllowAt 在指定时间尝试为 key 取出一个令牌
+func (l *KeyedLimiter) AllowAt(key string, now time.Time) bool {
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= pruneThreshold {
+			l.prune(now)
+		}
+		b = NewBucket(l.perMinute, l.burst)
+		l.buckets[key] = b
+	}
+	l.mu.Unlock()
+
+	return b.AllowAt(now)
+}
+
+// Refund 退回 key 的一个令牌
+func (l *KeyedLimiter) Refund(key string) {
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	l.mu.Unlock()
+	if ok {
+		b.Refund()
+	}
+}
+
+// prune 清理已经回满的桶，它们与新建的桶等价
+func (l *KeyedLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
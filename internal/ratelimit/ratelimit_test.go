@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(60, 2) // 每秒补充 1 个令牌
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	if !b.AllowAt(now) || !b.AllowAt(now) {
+		t.Fatal("burst of 2 should be allowed")
+	}
+	if b.AllowAt(now) {
+		t.Fatal("third request should be throttled")
+	}
+	if !b.AllowAt(now.Add(time.Second)) {
+		t.Fatal("token should be refilled after 1s")
+	}
+	if b.AllowAt(now.Add(time.Second)) {
+		t.Fatal("only one token should be refilled after 1s")
+	}
+}
+
+func TestBucketUnlimited(t *testing.T) {
+	b := NewBucket(0, 1)
+	for i := 0; i < 100; i++ {
+		if !b.Allow() {
+			t.Fatal("perMinute <= 0 should never throttle")
+		}
+	}
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	l := NewKeyedLimiter(1, 1)
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	if !l.AllowAt("alice", now) {
+		t.Fatal("alice first request should be allowed")
+	}
+	if l.AllowAt("alice", now) {
+		t.Fatal("alice second request should be throttled")
+	}
+	if !l.AllowAt("bob", now) {
+		t.Fatal("bob should have a separate bucket")
+	}
+	if !l.AllowAt("alice", now.Add(time.Minute)) {
+		t.Fatal("alice should be allowed again after a minute")
+	}
+}
+
+func TestKeyedLimiterRefund(t *testing.T) {
+	l := NewKeyedLimiter(1, 1)
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	if !l.AllowAt("alice", now) {
+		t.Fatal("alice first request should be allowed")
+	}
+	l.Refund("alice")
+	if !l.AllowAt("alice", now) {
+		t.Fatal("refunded token should be available")
+	}
+	l.Refund("alice")
+	l.Refund("alice")
+	if !l.AllowAt("alice", now) || l.AllowAt("alice", now) {
+		t.Fatal("refunds should not exceed the burst")
+	}
+}
@@ -0,0 +1,397 @@
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"wechatrobot/internal/filelock"
+	"wechatrobot/internal/holiday"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config LLM 用量统计配置
+type Config struct {
+	File             string  `mapstructure:"file"`               // 调用记录文件（JSON Lines），为空则只在内存中统计
+	DailyTokenBudget int     `mapstructure:"daily_token_budget"` // 每日 token 上限，0 表示不限制
+	DailyCostBudget  float64 `mapstructure:"daily_cost_budget"`  // 每日费用上限（元），0 表示不限制
+	RetentionDays    int     `mapstructure:"retention_days"`     // 记录保留天数（含今天），0 表示一直保留
+	Prices           []Price `mapstructure:"prices"`             // 各模型单价
+}
+
+// Price 模型单价（元/千 tokens）
+type Price struct {
+	Model      string  `mapstructure:"model"`
+	Prompt     float64 `mapstructure:"prompt"`
+	Completion float64 `mapstructure:"completion"`
+}
+
+// Record 一次 LLM 调用记录
+type Record struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	User             string    `json:"user,omitempty"` // 群聊提问的用户
+	Job              string    `json:"job,omitempty"`  // 定时任务名称
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	Success          bool      `json:"success"`
+	Cost             float64   `json:"cost"`
+}
+
+// Stats 聚合统计
+type Stats struct {
+	Calls            int     `json:"calls"`
+	Failures         int     `json:"failures"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// DailyStats 某一天的用量汇总
+type DailyStats struct {
+	Date       string            `json:"date"`
+	Total      Stats             `json:"total"`
+	ByUser     map[string]*Stats `json:"by_user"`
+	ByJob      map[string]*Stats `json:"by_job"`
+	ByProvider map[string]*Stats `json:"by_provider"`
+}
+
+// TotalTokens 返回输入与输出 token 之和
+func (s Stats) TotalTokens() int {
+	return s.PromptTokens + s.CompletionTokens
+}
+
+func (s *Stats) add(r Record) {
+	s.Calls++
+	if !r.Success {
+		s.Failures++
+	}
+	s.PromptTokens += r.PromptTokens
+	s.CompletionTokens += r.CompletionTokens
+	s.Cost += r.Cost
+}
+
+func newDailyStats(date string) *DailyStats {
+	return &DailyStats{
+		Date:       date,
+		ByUser:     make(map[string]*Stats),
+		ByJob:      make(map[string]*Stats),
+		ByProvider: make(map[string]*Stats),
+	}
+}
+
+func (d *DailyStats) add(r Record) {
+	d.Total.add(r)
+	addTo(d.ByProvider, r.Provider, r)
+	if r.User != "" {
+		addTo(d.ByUser, r.User, r)
+	}
+	if r.Job != "" {
+		addTo(d.ByJob, r.Job, r)
+	}
+}
+
+func addTo(m map[string]*Stats, key string, r Record) {
+	s, ok := m[key]
+	if !ok {
+		s = &Stats{}
+		m[key] = s
+	}
+	s.add(r)
+}
+
+// Tracker 记录并汇总 LLM 调用
+type Tracker struct {
+	mu   sync.Mutex
+	cfg  Config
+	days map[string]*DailyStats
+}
+
+var defaultTracker = &Tracker{days: make(map[string]*DailyStats)}
+
+// Init 按配置初始化默认统计器，并从记录文件恢复保留期内的汇总
+func Init(cfg Config) error {
+	t := &Tracker{cfg: cfg, days: make(map[string]*DailyStats)}
+	if err := t.load(); err != nil {
+		return err
+	}
+
+	defaultTracker.mu.Lock()
+	defaultTracker.cfg = t.cfg
+	defaultTracker.days = t.days
+	defaultTracker.mu.Unlock()
+	return nil
+}
+
+// load 从记录文件恢复汇总数据，有记录过期时重写记录文件
+func (t *Tracker) load() error {
+	if t.cfg.File == "" {
+		return nil
+	}
+
+	f, err := os.Open(t.cfg.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开用量记录文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			logrus.Warnf("跳过无法解析的用量记录: %v", err)
+			continue
+		}
+		t.day(r.Time).add(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.Close()
+
+	if t.prune() {
+		if err := compact(t.cfg.File, t.cutoff()); err != nil {
+			return fmt.Errorf("删除过期的用量记录失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// cutoff 返回保留期内最早的日期，不限制时返回空字符串
+func (t *Tracker) cutoff() string {
+	if t.cfg.RetentionDays <= 0 {
+		return ""
+	}
+	return time.Now().In(holiday.Location()).AddDate(0, 0, 1-t.cfg.RetentionDays).Format("2006-01-02")
+}
+
+// prune 删除超过保留天数的汇总，返回是否删除了汇总
+func (t *Tracker) prune() bool {
+	cutoff := t.cutoff()
+	pruned := false
+	for date := range t.days {
+		if date < cutoff {
+			delete(t.days, date)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// compact 在文件锁内重写记录文件，去掉 cutoff 之前的记录
+// 其他进程（如 weatherrobot send）追加的记录不在内存中，重写时从文件读取
+func compact(file, cutoff string) error {
+	unlock, err := filelock.Lock(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.Time.In(holiday.Location()).Format("2006-01-02") < cutoff {
+			continue
+		}
+		w.Write(scanner.Bytes())
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// today 返回配置时区的今天（YYYY-MM-DD），每日预算在配置时区的零点重置
+func today() string {
+	return time.Now().In(holiday.Location()).Format("2006-01-02")
+}
+
+// day 返回某个时间在配置时区所在日期的汇总，不存在则创建
+func (t *Tracker) day(tm time.Time) *DailyStats {
+	date := tm.In(holiday.Location()).Format("2006-01-02")
+	d, ok := t.days[date]
+	if !ok {
+		d = newDailyStats(date)
+		t.days[date] = d
+	}
+	return d
+}
+
+// cost 按配置的单价计算费用
+func (t *Tracker) cost(r Record) float64 {
+	for _, p := range t.cfg.Prices {
+		if p.Model == r.Model {
+			return float64(r.PromptTokens)/1000*p.Prompt + float64(r.CompletionTokens)/1000*p.Completion
+		}
+	}
+	return 0
+}
+
+// Add 记录一次 LLM 调用
+func (t *Tracker) Add(r Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	t.mu.Lock()
+	r.Cost = t.cost(r)
+	_, seen := t.days[r.Time.In(holiday.Location()).Format("2006-01-02")]
+	t.day(r.Time).add(r)
+	// 每天第一条记录时删除过期的汇总
+	expired := !seen && t.prune()
+	file, cutoff := t.cfg.File, t.cutoff()
+	t.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"provider":          r.Provider,
+		"model":             r.Model,
+		"user":              r.User,
+		"job":               r.Job,
+		"prompt_tokens":     r.PromptTokens,
+		"completion_tokens": r.CompletionTokens,
+		"latency_ms":        r.LatencyMs,
+		"success":           r.Success,
+	}).Info("LLM 调用记录")
+
+	if file == "" {
+		return
+	}
+	if err := appendRecord(file, r); err != nil {
+		logrus.Errorf("写入用量记录失败: %v", err)
+	}
+	if expired {
+		if err := compact(file, cutoff); err != nil {
+			logrus.Errorf("删除过期的用量记录失败: %v", err)
+		}
+	}
+}
+
+// appendRecord 在文件锁内追加一条记录到文件
+func appendRecord(file string, r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := filelock.Lock(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Daily 返回某一天（YYYY-MM-DD）的用量汇总副本
+func (t *Tracker) Daily(date string) DailyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.days[date]
+	if !ok {
+		return *newDailyStats(date)
+	}
+
+	copied := newDailyStats(date)
+	copied.Total = d.Total
+	for k, v := range d.ByUser {
+		s := *v
+		copied.ByUser[k] = &s
+	}
+	for k, v := range d.ByJob {
+		s := *v
+		copied.ByJob[k] = &s
+	}
+	for k, v := range d.ByProvider {
+		s := *v
+		copied.ByProvider[k] = &s
+	}
+	return *copied
+}
+
+// BudgetExceeded 检查今日用量是否超出预算
+func (t *Tracker) BudgetExceeded() bool {
+	t.mu.Lock()
+	cfg := t.cfg
+	t.mu.Unlock()
+
+	stats := t.Daily(today())
+	if cfg.DailyTokenBudget > 0 && stats.Total.TotalTokens() >= cfg.DailyTokenBudget {
+		return true
+	}
+	if cfg.DailyCostBudget > 0 && stats.Total.Cost >= cfg.DailyCostBudget {
+		return true
+	}
+	return false
+}
+
+// Add 使用默认统计器记录一次 LLM 调用
+func Add(r Record) {
+	defaultTracker.Add(r)
+}
+
+// Daily 返回默认统计器中某一天的用量汇总
+func Daily(date string) DailyStats {
+	return defaultTracker.Daily(date)
+}
+
+// BudgetExceeded 检查默认统计器今日用量是否超出预算
+func BudgetExceeded() bool {
+	return defaultTracker.BudgetExceeded()
+}
+
+// HandleUsage 以 JSON 返回某天的用量汇总，参数 date 默认为配置时区的今天
+func HandleUsage(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = today()
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "invalid date, want YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(Daily(date)); err != nil {
+		logrus.Errorf("输出用量汇总失败: %v", err)
+	}
+}
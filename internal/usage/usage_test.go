@@ -0,0 +1,124 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wechatrobot/internal/holiday"
+)
+
+func TestTrackerAggregatesAndBudget(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "usage.jsonl")
+	tracker := &Tracker{
+		cfg: Config{
+			File:             file,
+			DailyTokenBudget: 1000,
+			Prices:           []Price{{Model: "m", Prompt: 1, Completion: 2}},
+		},
+		days: make(map[string]*DailyStats),
+	}
+
+	now := time.Now()
+	tracker.Add(Record{Time: now, Provider: "Doubao", Model: "m", User: "alice", PromptTokens: 300, CompletionTokens: 200, Success: true})
+	tracker.Add(Record{Time: now, Provider: "OpenAI", Model: "other", Job: "off_work", PromptTokens: 100, Success: false})
+
+	today := tracker.Daily(now.Format("2006-01-02"))
+	if today.Total.Calls != 2 || today.Total.Failures != 1 {
+		t.Errorf("calls/failures = %d/%d, want 2/1", today.Total.Calls, today.Total.Failures)
+	}
+	if got := today.ByUser["alice"].TotalTokens(); got != 500 {
+		t.Errorf("alice tokens = %d, want 500", got)
+	}
+	if got := today.ByJob["off_work"].Calls; got != 1 {
+		t.Errorf("offwork calls = %d, want 1", got)
+	}
+	if got := today.Total.Cost; got != 0.7 {
+		t.Errorf("cost = %v, want 0.7", got)
+	}
+	if tracker.BudgetExceeded() {
+		t.Error("budget should not be exceeded at 600 tokens")
+	}
+
+	tracker.Add(Record{Time: now, Provider: "Doubao", Model: "m", PromptTokens: 400, Success: true})
+	if !tracker.BudgetExceeded() {
+		t.Error("budget should be exceeded at 1000 tokens")
+	}
+
+	// 从文件恢复后汇总应一致
+	restored := &Tracker{cfg: Config{File: file}, days: make(map[string]*DailyStats)}
+	if err := restored.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := restored.Daily(now.Format("2006-01-02")).Total.TotalTokens(); got != 1000 {
+		t.Errorf("restored tokens = %d, want 1000", got)
+	}
+}
+
+func TestTrackerUsesConfiguredTimezone(t *testing.T) {
+	defer holiday.SetLocation(holiday.Location())
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holiday.SetLocation(shanghai)
+
+	tracker := &Tracker{days: make(map[string]*DailyStats)}
+	// UTC 10 月 18 日 17:00 是北京时间 10 月 19 日 01:00，应计入 19 日
+	tracker.Add(Record{Time: time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC), Provider: "Doubao", PromptTokens: 100, Success: true})
+	tracker.Add(Record{Time: time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC), Provider: "Doubao", PromptTokens: 200, Success: true})
+
+	if got := tracker.Daily("2026-10-19").Total.TotalTokens(); got != 300 {
+		t.Errorf("2026-10-19 tokens = %d, want 300", got)
+	}
+	if got := tracker.Daily("2026-10-18").Total.Calls; got != 0 {
+		t.Errorf("2026-10-18 calls = %d, want 0", got)
+	}
+}
+
+func TestTrackerRetention(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "usage.jsonl")
+	now := time.Now()
+	old := Record{Time: now.AddDate(0, 0, -10), Provider: "Doubao", PromptTokens: 100, Success: true}
+	recent := Record{Time: now.AddDate(0, 0, -1), Provider: "Doubao", PromptTokens: 200, Success: true}
+	for _, r := range []Record{old, recent} {
+		if err := appendRecord(file, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 恢复时删除过期的汇总和文件中的记录
+	tracker := &Tracker{cfg: Config{File: file, RetentionDays: 7}, days: make(map[string]*DailyStats)}
+	if err := tracker.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := tracker.Daily(old.Time.In(holiday.Location()).Format("2006-01-02")).Total.Calls; got != 0 {
+		t.Errorf("expired day has %d calls, want 0", got)
+	}
+	if got := tracker.Daily(recent.Time.In(holiday.Location()).Format("2006-01-02")).Total.Calls; got != 1 {
+		t.Errorf("recent day has %d calls, want 1", got)
+	}
+	if n := countLines(t, file); n != 1 {
+		t.Errorf("file has %d records after load, want 1", n)
+	}
+
+	// 运行中写入的过期记录在下一天的第一条记录时删除
+	tracker.Add(Record{Time: now.AddDate(0, 0, -30), Provider: "Doubao", Success: true})
+	tracker.Add(Record{Time: now, Provider: "Doubao", Success: true})
+	if n := countLines(t, file); n != 2 {
+		t.Errorf("file has %d records after add, want 2", n)
+	}
+	if len(tracker.days) != 2 {
+		t.Errorf("tracker keeps %d days, want 2", len(tracker.days))
+	}
+}
+
+func countLines(t *testing.T, file string) int {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
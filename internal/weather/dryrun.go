@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DryRunSender 把消息写到 W 而不发送到群，用于调整消息格式
+type DryRunSender struct {
+	W     io.Writer
+	Group string // 输出中标注的群名
+
+	mu *sync.Mutex // 按群复制的 Sender 共用，避免多条消息交错
+}
+
+// NewDryRunSender 创建写到 w 的 DryRunSender
+func NewDryRunSender(w io.Writer) *DryRunSender {
+	return &DryRunSender{W: w, mu: &sync.Mutex{}}
+}
+
+// OpenDryRunSender 按输出目标创建 DryRunSender："" 或 "-" 为标准输出，否则追加写入文件
+// 返回的 close 用于关闭文件
+func OpenDryRunSender(output string) (s *DryRunSender, close func() error, err error) {
+	if output == "" || output == "-" {
+		return NewDryRunSender(os.Stdout), func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开 dry-run 输出文件失败: %w", err)
+	}
+	return NewDryRunSender(f), f.Close, nil
+}
+
+// ForGroup 返回在输出中标注群名的 Sender
+func (s *DryRunSender) ForGroup(name string) Sender {
+	c := *s
+	c.Group = name
+	return &c
+}
+
+// Send 输出消息
+func (s *DryRunSender) Send(ctx context.Context, content string, mentionUsers []string) error {
+	var b strings.Builder
+	b.WriteString("----- " + time.Now().Format("2006-01-02 15:04:05"))
+	if s.Group != "" {
+		b.WriteString(" [" + s.Group + "]")
+	}
+	b.WriteString(" -----\n")
+	if len(mentionUsers) > 0 {
+		b.WriteString("提醒成员: " + strings.Join(mentionUsers, ", ") + "\n")
+	}
+	b.WriteString(content + "\n\n")
+
+	if s.mu != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	_, err := io.WriteString(s.W, b.String())
+	return err
+}
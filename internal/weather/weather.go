@@ -1,12 +1,18 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 	"wechatrobot/internal/config"
 	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+
+	"github.com/sirupsen/logrus"
 )
 
 type WeatherResponse struct {
@@ -72,64 +78,188 @@ type LivingIndicesResponse struct {
 	} `json:"daily"`
 }
 
-func GetWeather(location, weatherType string) (*WeatherResponse, error) {
-
-	fmt.Println("开始请求天气接口")
-
-	url := fmt.Sprintf("https://api.qweather.com/v7/weather/now?location=%s&key=%s", location, config.Cfg.WeatherAPIKey)
+func GetWeather(ctx context.Context, location, weatherType string) (*WeatherResponse, error) {
+	endpoint := "now"
+	url := fmt.Sprintf("https://api.qweather.com/v7/weather/now?location=%s&key=%s", location, config.Get().WeatherAPIKey)
 
 	if weatherType == "7d" {
-		url = fmt.Sprintf("https://api.qweather.com/v7/weather/7d?location=%s&key=%s", location, config.Cfg.WeatherAPIKey)
+		endpoint = "7d"
+		url = fmt.Sprintf("https://api.qweather.com/v7/weather/7d?location=%s&key=%s", location, config.Get().WeatherAPIKey)
 
 	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := qweatherGet(ctx, endpoint, url)
 	if err != nil {
 		return nil, err
 	}
-
-	// 打印 API 响应
-	fmt.Println("===API 响应===: ", string(body))
+	log.From(ctx).WithField(log.FieldLocation, location).Debugf("和风天气 %s 响应: %s", endpoint, body)
 
 	var weatherResponse WeatherResponse
 	if err := json.Unmarshal(body, &weatherResponse); err != nil {
 		return nil, err
 	}
 
-	fmt.Println("天气获取成功")
-
 	return &weatherResponse, nil
 }
 
-func GetLivingIndices(location string) (*LivingIndicesResponse, error) {
+func GetLivingIndices(ctx context.Context, location string) (*LivingIndicesResponse, error) {
 
 	// https://api.qweather.com/v7/indices/1d?type=1,2&location=101010100
-	url := fmt.Sprintf("https://api.qweather.com/v7/indices/1d?type=1,2&key=%s&location=%s", config.Cfg.WeatherAPIKey, location)
-	fmt.Println("开始请求生活指数接口")
-	fmt.Println("==url===: ", url)
-	resp, err := http.Get(url)
+	url := fmt.Sprintf("https://api.qweather.com/v7/indices/1d?type=1,2&key=%s&location=%s", config.Get().WeatherAPIKey, location)
+	body, err := qweatherGet(ctx, "indices", url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	log.From(ctx).WithField(log.FieldLocation, location).Debugf("和风天气 indices 响应: %s", body)
+
+	var indicesResponse LivingIndicesResponse
+	if err := json.Unmarshal(body, &indicesResponse); err != nil {
+		return nil, err
+	}
+	return &indicesResponse, nil
+}
+
+// 历史天气响应（和风天气只提供最近 10 天的数据）
+type HistoricalWeatherResponse struct {
+	Code         string `json:"code"`
+	WeatherDaily struct {
+		Date    string `json:"date"`
+		TempMax string `json:"tempMax"`
+		TempMin string `json:"tempMin"`
+		Precip  string `json:"precip"`
+	} `json:"weatherDaily"`
+	WeatherHourly []struct {
+		Time string `json:"time"`
+		Temp string `json:"temp"`
+		Text string `json:"text"`
+	} `json:"weatherHourly"`
+}
+
+// DominantText 返回当天出现次数最多的天气状况
+func (h *HistoricalWeatherResponse) DominantText() string {
+	counts := make(map[string]int)
+	best := ""
+	for _, hour := range h.WeatherHourly {
+		counts[hour.Text]++
+		if best == "" || counts[hour.Text] > counts[best] {
+			best = hour.Text
+		}
+	}
+	return best
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// GetHistoricalWeather 获取某一天的历史天气
+func GetHistoricalWeather(ctx context.Context, location string, date time.Time) (*HistoricalWeatherResponse, error) {
+	url := fmt.Sprintf("https://api.qweather.com/v7/historical/weather?location=%s&date=%s&key=%s", location, date.Format("20060102"), config.Get().WeatherAPIKey)
+	body, err := qweatherGet(ctx, "historical", url)
 	if err != nil {
 		return nil, err
 	}
+	log.From(ctx).WithField(log.FieldLocation, location).Debugf("和风天气 historical %s 响应: %s", date.Format("2006-01-02"), body)
 
-	var indicesResponse LivingIndicesResponse
-	if err := json.Unmarshal(body, &indicesResponse); err != nil {
+	var historical HistoricalWeatherResponse
+	if err := json.Unmarshal(body, &historical); err != nil {
 		return nil, err
 	}
-	fmt.Println("生活指数获取成功")
-	fmt.Println("===indicesResponse===: ", indicesResponse)
-	return &indicesResponse, nil
+	if historical.Code != "200" {
+		return nil, fmt.Errorf("历史天气接口返回错误码: %s", historical.Code)
+	}
+	return &historical, nil
+}
+
+// Ping 查询第一个城市的实时天气，检查和风天气接口是否可用、API Key 是否有效
+func Ping(ctx context.Context) error {
+	cfg := config.Get()
+	locations := cfg.EffectiveGroups()[0].Locations
+	if len(locations) == 0 {
+		return fmt.Errorf("未配置城市")
+	}
+	url := fmt.Sprintf("https://api.qweather.com/v7/weather/now?location=%s&key=%s", locations[0], cfg.WeatherAPIKey)
+	_, err := qweatherGet(ctx, "ping", url)
+	return err
+}
+
+// 预览时复用的和风天气响应的有效期
+const cacheTTL = 10 * time.Minute
+
+type cachedKey struct{}
+
+// WithCache 让 ctx 中的和风天气请求优先使用 cacheTTL 内的响应（包括定时任务查询的结果），
+// 用于预览，避免反复请求消耗接口额度
+func WithCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cachedKey{}, true)
+}
+
+type cachedResponse struct {
+	body    []byte
+	fetched time.Time
+}
+
+var (
+	cacheMu   sync.Mutex
+	responses = make(map[string]cachedResponse)
+)
+
+// cached 返回 url 在有效期内的响应
+func cached(url string, now time.Time) ([]byte, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	c, ok := responses[url]
+	if !ok || now.Sub(c.fetched) > cacheTTL {
+		return nil, false
+	}
+	return c.body, true
+}
+
+// store 保存响应，同时删除过期的响应
+func store(url string, body []byte, now time.Time) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for k, c := range responses {
+		if now.Sub(c.fetched) > cacheTTL {
+			delete(responses, k)
+		}
+	}
+	responses[url] = cachedResponse{body: body, fetched: now}
+}
+
+// qweatherGet 请求和风天气接口并记录耗时和失败次数，endpoint 用于区分接口
+// ctx 由 WithCache 创建时优先使用缓存的响应
+func qweatherGet(ctx context.Context, endpoint, url string) ([]byte, error) {
+	if ctx.Value(cachedKey{}) != nil {
+		if body, ok := cached(url, time.Now()); ok {
+			return body, nil
+		}
+	}
+	start := time.Now()
+	body, err := func() ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("和风天气接口返回状态码: %d", resp.StatusCode)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}()
+	// 接口出错时 HTTP 状态码也可能是 200，错误码在 code 字段中
+	if err == nil {
+		var status struct {
+			Code string `json:"code"`
+		}
+		if json.Unmarshal(body, &status) == nil && status.Code != "" && status.Code != "200" {
+			err = fmt.Errorf("和风天气接口返回错误码: %s", status.Code)
+		}
+	}
+	metrics.ObserveQWeather(endpoint, start, err)
+	if err == nil {
+		store(url, body, time.Now())
+	}
+	return body, err
 }
 
 func GetCityName(location string) string {
@@ -147,5 +277,5 @@ func GetCityName(location string) string {
 
 func SendErrorAlert(err error) {
 	// 这里可以实现发送错误警报的逻辑，例如通过企业微信发送
-	log.Error("错误警报: ", err)
+	logrus.Error("错误警报: ", err)
 }
@@ -2,11 +2,14 @@ package weather
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"wechatrobot/internal/config"
 	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
 )
 
 type WecomMessage struct {
@@ -17,7 +20,36 @@ type WecomMessage struct {
 	} `json:"text"`
 }
 
-func SendWecomMessage(content string, mentionUsers []string) error {
+// Sender 消息发送方式，定时任务通过它发送消息，测试时可替换
+type Sender interface {
+	Send(ctx context.Context, content string, mentionUsers []string) error
+}
+
+// GroupSender 可以按群区分的 Sender，定时任务向每个群发送前调用 ForGroup
+type GroupSender interface {
+	ForGroup(name string) Sender
+}
+
+// WecomSender 通过企业微信群机器人 webhook 发送，Webhook 为空时使用配置中的地址
+type WecomSender struct {
+	Webhook string
+}
+
+// Send 发送文本消息
+func (s WecomSender) Send(ctx context.Context, content string, mentionUsers []string) error {
+	webhook := s.Webhook
+	if webhook == "" {
+		webhook = config.Get().WecomWebhook
+	}
+	return sendWecomMessage(ctx, webhook, content, mentionUsers)
+}
+
+// SendWecomMessage 通过配置的 webhook 发送文本消息
+func SendWecomMessage(ctx context.Context, content string, mentionUsers []string) error {
+	return sendWecomMessage(ctx, config.Get().WecomWebhook, content, mentionUsers)
+}
+
+func sendWecomMessage(ctx context.Context, webhook, content string, mentionUsers []string) error {
 	message := WecomMessage{
 		MsgType: "text",
 	}
@@ -29,16 +61,37 @@ func SendWecomMessage(content string, mentionUsers []string) error {
 		return err
 	}
 
-	resp, err := http.Post(config.Cfg.WecomWebhook, "application/json", bytes.NewBuffer(messageBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewBuffer(messageBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		metrics.ObserveWecomSend("error")
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.ObserveWecomHTTPStatus(resp.StatusCode)
 		return fmt.Errorf("发送消息失败，状态码: %d", resp.StatusCode)
 	}
 
-	log.Info("消息发送成功")
+	// 企业微信的错误（如 key 无效、发送频率超限）通过 errcode 返回，HTTP 状态码仍为 200
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		metrics.ObserveWecomSend("error")
+		return fmt.Errorf("解析发送结果失败: %w", err)
+	}
+	metrics.ObserveWecomSend(strconv.Itoa(result.ErrCode))
+	if result.ErrCode != 0 {
+		return fmt.Errorf("发送消息失败，errcode: %d，errmsg: %s", result.ErrCode, result.ErrMsg)
+	}
+
+	log.From(ctx).Info("消息发送成功")
 	return nil
 }
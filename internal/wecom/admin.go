@@ -0,0 +1,52 @@
+package wecom
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"wechatrobot/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// adminOnly 限制管理接口的访问：配置了 admin.token 时要求请求头 Authorization: Bearer <token>，
+// 否则只允许本机访问。管理接口与企业微信回调共用端口，回调地址通常暴露在公网
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := config.Get().Admin.Token
+		if token == "" {
+			if !isLoopback(r.RemoteAddr) {
+				logrus.Warnf("拒绝来自 %s 的管理接口请求 %s：未配置 admin.token，只允许本机访问", r.RemoteAddr, r.URL.Path)
+				http.Error(w, "forbidden: set admin.token to access this endpoint remotely", http.StatusForbidden)
+				return
+			}
+		} else if !validToken(r.Header.Get("Authorization"), token) {
+			logrus.Warnf("拒绝来自 %s 的管理接口请求 %s：令牌无效", r.RemoteAddr, r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validToken 检查 Authorization 请求头中的 Bearer 令牌
+func validToken(header, token string) bool {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	got := strings.TrimSpace(header[len(prefix):])
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// isLoopback 检查请求是否来自本机
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
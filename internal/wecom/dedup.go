@@ -0,0 +1,206 @@
+package wecom
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dedupEntry 已处理消息的记录
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// dedupStore 带过期时间的 LRU，记录已处理过的消息，可选持久化到文件
+type dedupStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	file     string
+	order    *list.List // 最近使用的在前
+	items    map[string]*list.Element
+	appended int // 上次重写文件后追加的记录数，超过容量时重写文件
+}
+
+// newDedupStore 创建去重存储，file 不为空时从文件恢复未过期的记录
+func newDedupStore(ttl time.Duration, capacity int, file string) *dedupStore {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	s := &dedupStore{
+		ttl:      ttl,
+		capacity: capacity,
+		file:     file,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+	if file != "" {
+		if err := s.load(time.Now()); err != nil {
+			logrus.Warnf("恢复消息去重记录失败: %v", err)
+		}
+	}
+	return s
+}
+
+// messageKey 生成消息的去重 key，优先使用 MsgId
+// 没有 MsgId 时用发送人、发送时间和内容摘要，发送时间也没有时返回空字符串，不去重
+func messageKey(msg WecomIncomingMessage) string {
+	if msg.MsgID != "" {
+		return msg.MsgID
+	}
+	if msg.CreateTime == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(msg.Text.Content + "\x00" + msg.Content))
+	return fmt.Sprintf("%s:%d:%x", msg.FromUserID, msg.CreateTime, sum[:8])
+}
+
+// Seen 检查 key 是否已处理过，未处理过则记录下来
+func (s *dedupStore) Seen(key string) bool {
+	return s.SeenAt(key, time.Now())
+}
+
+// SeenAt 在指定时间检查并记录 key
+func (s *dedupStore) SeenAt(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		entry := el.Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			s.order.MoveToFront(el)
+			return true
+		}
+		s.remove(el)
+	}
+
+	entry := &dedupEntry{key: key, expires: now.Add(s.ttl)}
+	s.items[key] = s.order.PushFront(entry)
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	if s.file != "" {
+		if err := s.persist(entry); err != nil {
+			logrus.Warnf("持久化消息去重记录失败: %v", err)
+		}
+	}
+	return false
+}
+
+// Len 当前记录的消息数
+func (s *dedupStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *dedupStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*dedupEntry).key)
+}
+
+// persist 追加记录到文件，追加的记录超过容量时用内存中的记录重写文件，避免文件一直增长
+func (s *dedupStore) persist(entry *dedupEntry) error {
+	if s.appended >= s.capacity {
+		s.appended = 0
+		return s.compact()
+	}
+	s.appended++
+	return s.appendEntry(entry)
+}
+
+// appendEntry 追加一条记录到文件，格式为 "key\t过期时间戳"
+func (s *dedupStore) appendEntry(entry *dedupEntry) error {
+	f, err := os.OpenFile(s.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%d\n", entry.key, entry.expires.Unix())
+	return err
+}
+
+// load 从文件恢复未过期的记录，并重写文件去掉已过期的记录
+func (s *dedupStore) load(now time.Time) error {
+	if dir := filepath.Dir(s.file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(s.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		unix, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		expires := time.Unix(unix, 0)
+		if !now.Before(expires) {
+			continue
+		}
+		if el, ok := s.items[parts[0]]; ok {
+			s.remove(el)
+		}
+		s.items[parts[0]] = s.order.PushFront(&dedupEntry{key: parts[0], expires: expires})
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return s.compact()
+}
+
+// compact 用内存中的记录重写文件
+func (s *dedupStore) compact() error {
+	tmp := s.file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for el := s.order.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*dedupEntry)
+		fmt.Fprintf(w, "%s\t%d\n", entry.key, entry.expires.Unix())
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
@@ -0,0 +1,83 @@
+package wecom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupStore(t *testing.T) {
+	s := newDedupStore(time.Minute, 2, "")
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	if s.SeenAt("a", now) {
+		t.Fatal("first message should not be seen")
+	}
+	if !s.SeenAt("a", now.Add(30*time.Second)) {
+		t.Fatal("retry within TTL should be seen")
+	}
+	if s.SeenAt("a", now.Add(2*time.Minute)) {
+		t.Fatal("message should expire after TTL")
+	}
+
+	// 容量为 2，最久未使用的记录会被淘汰
+	s.SeenAt("b", now.Add(2*time.Minute))
+	s.SeenAt("c", now.Add(2*time.Minute))
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+	if s.SeenAt("a", now.Add(2*time.Minute)) {
+		t.Fatal("evicted message should not be seen")
+	}
+}
+
+func TestDedupStorePersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dedup.txt")
+	s := newDedupStore(time.Hour, 100, file)
+	s.Seen("msg-1")
+
+	restored := newDedupStore(time.Hour, 100, file)
+	if !restored.Seen("msg-1") {
+		t.Fatal("persisted message should be seen after restart")
+	}
+	if restored.Seen("msg-2") {
+		t.Fatal("new message should not be seen")
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	if got := messageKey(WecomIncomingMessage{MsgID: "m1", FromUserID: "u", CreateTime: 1}); got != "m1" {
+		t.Errorf("messageKey with MsgId = %q, want m1", got)
+	}
+	a := WecomIncomingMessage{FromUserID: "u", CreateTime: 1, Content: "@机器人 你好"}
+	b := WecomIncomingMessage{FromUserID: "u", CreateTime: 1, Content: "@机器人 明天呢"}
+	if ka, kb := messageKey(a), messageKey(b); !strings.HasPrefix(ka, "u:1:") || ka == kb || ka != messageKey(a) {
+		t.Errorf("messageKey without MsgId = %q, %q", ka, kb)
+	}
+	// 没有发送时间时不能用 "u:0"，否则之后该用户的消息都会被当成重复回调
+	if got := messageKey(WecomIncomingMessage{FromUserID: "u", Content: "@机器人 你好"}); got != "" {
+		t.Errorf("messageKey without MsgId and CreateTime = %q, want empty", got)
+	}
+}
+
+func TestDedupStoreCompactsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dedup.txt")
+	s := newDedupStore(time.Hour, 3, file)
+	for i := 0; i < 20; i++ {
+		s.Seen(fmt.Sprintf("msg-%d", i))
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n > 6 {
+		t.Errorf("file has %d lines, want at most twice the capacity", n)
+	}
+	if restored := newDedupStore(time.Hour, 3, file); !restored.Seen("msg-19") {
+		t.Error("latest message should be seen after restart")
+	}
+}
@@ -1,13 +1,25 @@
 package wecom
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"wechatrobot/internal/ai"
 	"wechatrobot/internal/config"
+	"wechatrobot/internal/cronn"
+	"wechatrobot/internal/history"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/metrics"
+	"wechatrobot/internal/ratelimit"
+	"wechatrobot/internal/usage"
 	"wechatrobot/internal/weather"
 
 	"github.com/sirupsen/logrus"
@@ -27,13 +39,153 @@ type WecomIncomingMessage struct {
 	} `json:"Text"`
 }
 
-// StartWecomServer 启动企业微信消息接收服务
-func StartWecomServer(port string) {
+const (
+	// 被限流时的回复
+	throttledReply = "请稍后再问，我有点忙不过来啦～"
+	// 开始调用 AI 前的提示
+	thinkingReply = "思考中…"
+)
+
+var (
+	pool          *workerPool
+	dedup         *dedupStore
+	userLimiter   *ratelimit.KeyedLimiter
+	globalLimiter *ratelimit.Bucket
+	// 每个用户每分钟最多收到一次限流提示，避免提示本身刷屏
+	noticeLimiter = ratelimit.NewKeyedLimiter(1, 1)
+	// 所有用户的限流提示总数（chat_limit.notice_per_minute），群机器人每分钟最多发 20 条消息，提示不能挤占正常回复
+	noticeBudget *ratelimit.Bucket
+
+	received   int64 // 收到的 @机器人 提问数
+	throttled  int64 // 被限流的提问数
+	duplicated int64 // 因重复回调被丢弃的消息数
+
+	// server 在 StartWecomServer 中创建，Shutdown 在主 goroutine 读取，由 serverMu 保护
+	serverMu     sync.Mutex
+	server       *http.Server
+	shuttingDown bool // Shutdown 已调用，StartWecomServer 不再启动服务
+	// 处理提问、请假命令时使用的 context，Shutdown 超时时取消，中止 AI 调用和消息发送
+	chatCtx, cancelChat = context.WithCancel(context.Background())
+	// 请假命令、限流提示等后台发送，Shutdown 时等待它们完成
+	background sync.WaitGroup
+)
+
+// ChatStats 群聊提问处理统计
+type ChatStats struct {
+	Received      int64 `json:"received"`
+	Throttled     int64 `json:"throttled"`
+	Duplicated    int64 `json:"duplicated"`
+	QueueLength   int   `json:"queue_length"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Active        int   `json:"active"`
+}
+
+// Stats 返回群聊提问处理统计
+func Stats() ChatStats {
+	stats := ChatStats{
+		Received:   atomic.LoadInt64(&received),
+		Throttled:  atomic.LoadInt64(&throttled),
+		Duplicated: atomic.LoadInt64(&duplicated),
+	}
+	if pool != nil {
+		stats.QueueLength = pool.QueueLength()
+		stats.QueueCapacity = pool.QueueCapacity()
+		stats.Active = pool.Active()
+	}
+	return stats
+}
+
+// HandleChatStats 以 JSON 返回群聊提问处理统计
+func HandleChatStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(Stats()); err != nil {
+		logrus.Errorf("输出群聊统计失败: %v", err)
+	}
+}
+
+// initChat 按配置初始化去重存储、限流器和 worker 池
+func initChat(cfg config.ChatLimitConfig, dedupCfg config.DedupConfig) {
+	dedup = newDedupStore(dedupCfg.TTL, dedupCfg.Capacity, dedupCfg.File)
+	userLimiter = ratelimit.NewKeyedLimiter(cfg.UserPerMinute, cfg.UserBurst)
+	globalLimiter = ratelimit.NewBucket(cfg.GlobalPerMinute, cfg.GlobalBurst)
+	noticeBudget = ratelimit.NewBucket(cfg.NoticePerMinute, cfg.NoticePerMinute)
+	pool = newWorkerPool(cfg.Workers, cfg.QueueSize, func(task chatTask) {
+		ProcessUserMessage(withRequest(chatCtx, task.requestID, task.userID), task.question, task.userID)
+	})
+}
+
+// StartWecomServer 启动企业微信消息接收服务，监听失败时返回错误
+func StartWecomServer(port string) error {
+	cfg := config.Get()
+	initChat(cfg.ChatLimit, cfg.Dedup)
+
 	http.HandleFunc("/wecom/message", HandleWecomMessage)
+	http.HandleFunc("/usage", adminOnly(usage.HandleUsage))
+	http.HandleFunc("/chat/stats", adminOnly(HandleChatStats))
+	http.HandleFunc("/preview", adminOnly(cronn.HandlePreview))
+	http.HandleFunc("/history", adminOnly(history.HandleHistory))
+	http.Handle("/metrics", metrics.Handler())
+	http.HandleFunc("/calendar.ics", holiday.ICSHandler(func() []holiday.Holiday { return config.Get().Holidays }))
+
+	serverMu.Lock()
+	if shuttingDown {
+		serverMu.Unlock()
+		return nil
+	}
+	srv := &http.Server{Addr: ":" + port}
+	server = srv
+	serverMu.Unlock()
+
 	logrus.Infof("企业微信消息服务启动，监听端口 %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		logrus.Errorf("企业微信消息服务启动失败: %v", err)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("企业微信消息服务启动失败: %w", err)
 	}
+	return nil
+}
+
+// Shutdown 停止接收消息，等待正在处理的提问和请假命令回复完成
+// ctx 到期时取消 AI 调用和消息发送并返回 ctx 的错误
+func Shutdown(ctx context.Context) error {
+	serverMu.Lock()
+	shuttingDown = true
+	srv := server
+	serverMu.Unlock()
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			cancelChat()
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		if pool != nil {
+			pool.Close()
+		}
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		cancelChat()
+		return ctx.Err()
+	}
+}
+
+// withRequest 给 ctx 加上回调消息 ID 和发消息的用户，处理这条消息的日志都带上这两个字段
+func withRequest(ctx context.Context, requestID, userID string) context.Context {
+	return log.WithFields(ctx, logrus.Fields{log.FieldRequestID: requestID, log.FieldUser: userID})
+}
+
+// goBackground 在后台执行 f，Shutdown 时等待它完成
+func goBackground(f func(ctx context.Context)) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		f(chatCtx)
+	}()
 }
 
 // HandleWecomMessage 处理企业微信的消息 webhook
@@ -58,6 +210,8 @@ func HandleWecomMessage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to parse message", http.StatusBadRequest)
 		return
 	}
+	requestID := messageKey(msg)
+	ctx := withRequest(r.Context(), requestID, msg.FromUserID)
 
 	// 只处理文本消息
 	if msg.MsgType != "text" {
@@ -66,6 +220,19 @@ func HandleWecomMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 企业微信重试的回调直接丢弃，避免重复回复；无法生成去重 key 的消息不去重
+	if dedup == nil {
+		initChat(config.Get().ChatLimit, config.Get().Dedup)
+	}
+	if requestID != "" && dedup.Seen(requestID) {
+		atomic.AddInt64(&duplicated, 1)
+		metrics.ChatDuplicated()
+		log.From(ctx).Info("消息已处理过，忽略重复回调")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+
 	// 获取消息内容
 	var messageContent string
 	if msg.Text.Content != "" {
@@ -78,7 +245,8 @@ func HandleWecomMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logrus.Infof("收到来自 %s 的消息: %s", msg.FromUserID, messageContent)
+	log.From(ctx).WithField("length", len([]rune(messageContent))).Info("收到消息")
+	log.From(ctx).Debugf("消息内容: %s", messageContent)
 
 	// 检查是否是 @机器人 的消息
 	question := extractQuestion(messageContent)
@@ -89,35 +257,186 @@ func HandleWecomMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 异步处理消息，快速响应
-	go ProcessUserMessage(question, msg.FromUserID)
+	// 请假命令不需要调用 AI，直接处理；请假回复同样占用群机器人的发送额度，和提问一起限流
+	if isLeaveCommand(question) {
+		if allow(ctx, msg.FromUserID) {
+			goBackground(func(bg context.Context) {
+				handleLeaveCommand(withRequest(bg, requestID, msg.FromUserID), question, msg.FromUserID)
+			})
+		} else {
+			atomic.AddInt64(&throttled, 1)
+			metrics.ChatThrottled()
+			goBackground(func(bg context.Context) { replyThrottled(withRequest(bg, requestID, msg.FromUserID), msg.FromUserID) })
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+
+	// 限流后放入队列异步处理，快速响应
+	atomic.AddInt64(&received, 1)
+	metrics.ChatReceived()
+	if !enqueue(ctx, chatTask{requestID: requestID, question: question, userID: msg.FromUserID}) {
+		atomic.AddInt64(&throttled, 1)
+		metrics.ChatThrottled()
+		goBackground(func(bg context.Context) { replyThrottled(withRequest(bg, requestID, msg.FromUserID), msg.FromUserID) })
+	}
 
 	// 立即响应 200 OK
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
 
-// ProcessUserMessage 处理用户消息并通过 AI 生成回复
-func ProcessUserMessage(userMessage, userID string) {
-	// 使用豆包 AI 来回答问题
-	reply, err := ai.AskDoubao(userMessage, config.Cfg.DoubaoURL, config.Cfg.DoubaoAPIKey, config.Cfg.DoubaoModel)
-	if err != nil {
-		logrus.Warnf("调用豆包 AI 失败: %v，尝试回退到 OpenAI", err)
-		reply, err = ai.AskOpenAI(userMessage, config.Cfg.OpenAIAPIKey)
-		if err != nil {
-			logrus.Errorf("调用 OpenAI 也失败: %v", err)
-			reply = "抱歉，我现在无法处理您的问题，请稍后再试。"
+// allow 检查用户和全局限流，提问和请假命令共用，被限流时返回 false
+func allow(ctx context.Context, userID string) bool {
+	if !userLimiter.Allow(userID) {
+		log.From(ctx).Warn("用户提问过于频繁，已限流")
+		return false
+	}
+	if !globalLimiter.Allow() {
+		log.From(ctx).Warn("全局提问过于频繁，已限流")
+		return false
+	}
+	return true
+}
+
+// enqueue 经过用户和全局限流后放入处理队列，被限流或队列已满时返回 false
+func enqueue(ctx context.Context, task chatTask) bool {
+	if !allow(ctx, task.userID) {
+		return false
+	}
+	if !pool.Submit(task) {
+		log.From(ctx).Warnf("提问队列已满（%d），已限流", pool.QueueCapacity())
+		return false
+	}
+
+	log.From(ctx).Debugf("提问已入队，当前队列长度 %d", pool.QueueLength())
+	return true
+}
+
+// replyThrottled 提示被限流的用户稍后再问
+func replyThrottled(ctx context.Context, userID string) {
+	if !allowNotice(userID) {
+		log.From(ctx).Debug("限流提示过于频繁，不再提示")
+		return
+	}
+
+	content := fmt.Sprintf("@%s %s", userID, throttledReply)
+	if err := weather.SendWecomMessage(ctx, content, nil); err != nil {
+		log.From(ctx).Errorf("发送限流提示失败: %v", err)
+	}
+}
+
+// allowNotice 检查是否给用户发送限流提示，同时受每个用户和全局的提示频率限制
+// 全局额度用完时退回用户的令牌，额度恢复后该用户仍能收到提示
+func allowNotice(userID string) bool {
+	if !noticeLimiter.Allow(userID) {
+		return false
+	}
+	if !noticeBudget.Allow() {
+		noticeLimiter.Refund(userID)
+		return false
+	}
+	return true
+}
+
+// ProcessUserMessage 处理用户消息并通过 AI 生成回复，ctx 取消时中止 AI 调用和发送
+func ProcessUserMessage(ctx context.Context, userMessage, userID string) {
+	var reply string
+	if usage.BudgetExceeded() {
+		// 今日 AI 预算已用完，不再调用 AI
+		log.From(ctx).Warn("今日 AI 用量已超出预算，拒绝回答")
+		reply = "AI额度已用完，明天再来找我聊天吧～"
+	} else {
+		// 先告诉用户正在思考，AI 回答通常需要 5-10 秒
+		if config.Get().Chat.ThinkingAck {
+			ack := fmt.Sprintf("@%s %s", userID, thinkingReply)
+			if err := weather.SendWecomMessage(ctx, ack, nil); err != nil {
+				log.From(ctx).Errorf("发送思考中提示失败: %v", err)
+			}
 		}
+		reply = askAI(ctx, userMessage, userID)
+	}
+
+	// 违禁词检查，命中则不发送原始回复
+	if err := ai.CheckBannedWords(reply, config.Get().BannedWords); err != nil {
+		log.From(ctx).WithFields(logrus.Fields{
+			"reason": err.Error(),
+			"length": len([]rune(reply)),
+		}).Warn("AI 回复未通过校验，已拒绝")
+		log.From(ctx).Debugf("被拒绝的 AI 回复: %s", reply)
+		reply = "抱歉，这个问题我暂时无法回答，换个问题试试吧。"
 	}
 
 	// 构造回复消息
 	responseContent := fmt.Sprintf("@%s\n\n%s", userID, reply)
 
 	// 发送回复
-	if err := weather.SendWecomMessage(responseContent, nil); err != nil {
-		logrus.Errorf("发送回复消息失败: %v", err)
+	if err := weather.SendWecomMessage(ctx, responseContent, nil); err != nil {
+		log.From(ctx).Errorf("发送回复消息失败: %v", err)
 	} else {
-		logrus.Infof("已向 %s 发送回复", userID)
+		log.From(ctx).Info("已发送回复")
+	}
+}
+
+// askAI 使用豆包 AI 来回答问题，失败时回退到 OpenAI
+// 整个回答受 chat.timeout 限制，流式模式下超时会返回已生成的部分内容
+func askAI(ctx context.Context, userMessage, userID string) string {
+	ctx, cancel := context.WithTimeout(ctx, chatTimeout())
+	defer cancel()
+
+	reply, err := askDoubao(ctx, userMessage, userID)
+	if err != nil && ctx.Err() == nil {
+		log.From(ctx).Warnf("调用豆包 AI 失败: %v，尝试回退到 OpenAI", err)
+		reply, err = askOpenAI(ctx, userMessage, userID)
+	}
+	if err == nil {
+		return reply
+	}
+
+	if ctx.Err() != nil {
+		log.From(ctx).Warnf("回答问题超时: %v", err)
+		if strings.TrimSpace(reply) != "" {
+			return reply + "\n\n（回答超时，内容可能不完整）"
+		}
+		return "抱歉，这个问题想得有点久，请稍后再试。"
+	}
+
+	log.From(ctx).Errorf("调用 OpenAI 也失败: %v", err)
+	return "抱歉，我现在无法处理您的问题，请稍后再试。"
+}
+
+// chatTimeout 返回单次回答的总超时
+func chatTimeout() time.Duration {
+	if timeout := config.Get().Chat.Timeout; timeout > 0 {
+		return timeout
+	}
+	return 60 * time.Second
+}
+
+func askDoubao(ctx context.Context, userMessage, userID string) (string, error) {
+	cfg := config.Get()
+	if !cfg.Chat.Stream {
+		return ai.AskDoubao(ctx, userMessage, userID, cfg.DoubaoURL, cfg.DoubaoAPIKey, cfg.DoubaoModel)
+	}
+	return ai.StreamDoubao(ctx, userMessage, userID, cfg.DoubaoURL, cfg.DoubaoAPIKey, cfg.DoubaoModel, logDelta(ctx))
+}
+
+func askOpenAI(ctx context.Context, userMessage, userID string) (string, error) {
+	cfg := config.Get()
+	if !cfg.Chat.Stream {
+		return ai.AskOpenAI(ctx, userMessage, userID, cfg.OpenAIAPIKey)
+	}
+	return ai.StreamOpenAI(ctx, userMessage, userID, cfg.OpenAIAPIKey, logDelta(ctx))
+}
+
+// logDelta 记录流式回答的增量内容
+// 群机器人 webhook 无法更新已发送的消息（template_card 更新需要应用消息接口），
+// 所以增量内容只用于日志，最终答案生成后一次性发送
+func logDelta(ctx context.Context) func(string) {
+	logger := log.From(ctx)
+	return func(delta string) {
+		logger.Debugf("回答的增量内容: %s", delta)
 	}
 }
 
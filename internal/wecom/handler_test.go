@@ -0,0 +1,159 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wechatrobot/internal/config"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/ratelimit"
+)
+
+func TestAskAITimeoutWithoutStream(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	old := config.Get()
+	defer config.Set(old)
+	config.Set(&config.Config{
+		DoubaoURL:    slow.URL,
+		DoubaoAPIKey: "test-key",
+		DoubaoModel:  "test-model",
+		Chat:         config.ChatConfig{Stream: false, Timeout: 100 * time.Millisecond},
+	})
+
+	start := time.Now()
+	reply := askAI(context.Background(), "今天天气怎么样", "zhangsan")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("askAI took %v, chat.timeout not applied", elapsed)
+	}
+	if reply != "抱歉，这个问题想得有点久，请稍后再试。" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+func TestAllowNoticeGlobalBudget(t *testing.T) {
+	defer func(keyed *ratelimit.KeyedLimiter, bucket *ratelimit.Bucket) {
+		noticeLimiter, noticeBudget = keyed, bucket
+	}(noticeLimiter, noticeBudget)
+	noticeLimiter = ratelimit.NewKeyedLimiter(1, 1)
+	noticeBudget = ratelimit.NewBucket(3, 3)
+
+	// 很多用户同时被限流时，提示总数也受限
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if allowNotice(fmt.Sprintf("user%d", i)) {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d notices, want 3", allowed)
+	}
+
+	// 全局额度用完时没有收到提示的用户，额度恢复后仍能收到提示
+	noticeBudget = ratelimit.NewBucket(3, 3)
+	if !allowNotice("user10") {
+		t.Error("user10 should get a notice after the budget refills")
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	old := config.Get()
+	defer config.Set(old)
+	ok := adminOnly(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		token  string // admin.token
+		remote string
+		auth   string
+		status int
+	}{
+		{"", "127.0.0.1:50000", "", http.StatusOK},
+		{"", "[::1]:50000", "", http.StatusOK},
+		{"", "203.0.113.7:50000", "", http.StatusForbidden},
+		{"s3cret", "203.0.113.7:50000", "Bearer s3cret", http.StatusOK},
+		{"s3cret", "203.0.113.7:50000", "Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", "127.0.0.1:50000", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		config.Set(&config.Config{Admin: config.AdminConfig{Token: tt.token}})
+		r := httptest.NewRequest(http.MethodGet, "/history", nil)
+		r.RemoteAddr = tt.remote
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		ok(w, r)
+		if w.Code != tt.status {
+			t.Errorf("token=%q remote=%s auth=%q: status %d, want %d", tt.token, tt.remote, tt.auth, w.Code, tt.status)
+		}
+	}
+}
+
+func TestLeaveCommandsThrottled(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text struct {
+				Content string `json:"content"`
+			} `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		sent = append(sent, msg.Text.Content)
+		mu.Unlock()
+		w.Write([]byte(`{"errcode":0}`))
+	}))
+	defer webhook.Close()
+
+	old := config.Get()
+	defer config.Set(old)
+	config.Set(&config.Config{
+		WecomWebhook: webhook.URL,
+		ChatLimit:    config.ChatLimitConfig{UserPerMinute: 1, UserBurst: 2, GlobalPerMinute: 10, GlobalBurst: 10, Workers: 1},
+	})
+	if err := holiday.InitLeaves(holiday.LeaveConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func(keyed *ratelimit.KeyedLimiter, bucket *ratelimit.Bucket) {
+		noticeLimiter, noticeBudget = keyed, bucket
+	}(noticeLimiter, noticeBudget)
+	noticeLimiter = ratelimit.NewKeyedLimiter(1, 1)
+	noticeBudget = ratelimit.NewBucket(3, 3)
+	initChat(config.Get().ChatLimit, config.Get().Dedup)
+	defer func() { dedup = nil }()
+
+	for i := 0; i < 10; i++ {
+		body := fmt.Sprintf(`{"MsgType":"text","MsgId":"leave-%d","FromUserID":"alice","Text":{"content":"@机器人 /销假"}}`, i)
+		w := httptest.NewRecorder()
+		HandleWecomMessage(w, httptest.NewRequest(http.MethodPost, "/wecom/message", strings.NewReader(body)))
+	}
+	background.Wait()
+
+	replies, notices := 0, 0
+	for _, content := range sent {
+		switch {
+		case strings.Contains(content, "撤销"):
+			replies++
+		case strings.Contains(content, throttledReply):
+			notices++
+		}
+	}
+	if replies != 2 || notices != 1 {
+		t.Errorf("sent %d leave replies and %d throttle notices, want 2 and 1: %q", replies, notices, sent)
+	}
+}
@@ -0,0 +1,131 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"wechatrobot/internal/holiday"
+	"wechatrobot/internal/log"
+	"wechatrobot/internal/weather"
+)
+
+const (
+	// 登记请假，如 "/请假 10-20 10-25"、"/请假 10-20"
+	leaveCommand = "/请假"
+	// 撤销尚未结束的请假
+	cancelLeaveCommand = "/销假"
+
+	leaveUsage = "请假格式：/请假 开始日期 [结束日期]，如 /请假 10-20 10-25；撤销请假：/销假"
+)
+
+// isLeaveCommand 检查是否为请假相关命令
+func isLeaveCommand(question string) bool {
+	return strings.HasPrefix(question, leaveCommand) || strings.HasPrefix(question, cancelLeaveCommand)
+}
+
+// handleLeaveCommand 处理请假命令并回复用户
+func handleLeaveCommand(ctx context.Context, question, userID string) {
+	reply := leaveReply(ctx, question, userID, holiday.SystemClock{}.Now())
+	content := fmt.Sprintf("@%s %s", userID, reply)
+	if err := weather.SendWecomMessage(ctx, content, nil); err != nil {
+		log.From(ctx).Errorf("发送请假回复失败: %v", err)
+	}
+}
+
+// leaveReply 执行请假命令，返回回复内容
+func leaveReply(ctx context.Context, question, userID string, now time.Time) string {
+	today := holiday.DateOf(now)
+
+	if strings.HasPrefix(question, cancelLeaveCommand) {
+		cancelled, err := holiday.CancelLeaves(userID, today)
+		if err != nil {
+			log.From(ctx).Errorf("撤销请假失败: %v", err)
+			return "撤销请假失败，请稍后再试"
+		}
+		if len(cancelled) == 0 {
+			return "没有找到可以撤销的请假"
+		}
+		log.From(ctx).Infof("撤销了 %d 条请假", len(cancelled))
+		return fmt.Sprintf("已撤销 %d 条请假，提醒会照常 @你", len(cancelled))
+	}
+
+	args := strings.Fields(strings.TrimPrefix(question, leaveCommand))
+	start, end, err := parseLeaveDates(args, today)
+	if err != nil {
+		return err.Error()
+	}
+
+	leave := holiday.Leave{User: userID, StartDate: start, EndDate: end}
+	if err := holiday.AddLeave(leave); err != nil {
+		log.From(ctx).Errorf("登记请假失败: %v", err)
+		return "登记请假失败，请稍后再试"
+	}
+	log.From(ctx).Infof("登记请假 %s 至 %s", start, end)
+
+	period := holiday.Festival{StartDate: start, EndDate: end}.Period()
+	return fmt.Sprintf("已登记请假：%s，期间的定时提醒不会 @你。祝假期愉快！", period)
+}
+
+// parseLeaveDates 解析请假的开始和结束日期
+// 日期可以是 MM-DD 或 YYYY-MM-DD；省略年份时取今天之后最近的日期，省略结束日期表示只请一天
+func parseLeaveDates(args []string, today holiday.Date) (holiday.Date, holiday.Date, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return holiday.Date{}, holiday.Date{}, errors.New(leaveUsage)
+	}
+
+	start, err := parseLeaveDate(args[0], today)
+	if err != nil {
+		return holiday.Date{}, holiday.Date{}, err
+	}
+	end := start
+	if len(args) == 2 {
+		if end, err = parseLeaveDate(args[1], start); err != nil {
+			return holiday.Date{}, holiday.Date{}, err
+		}
+	}
+
+	if end.Before(start) {
+		return holiday.Date{}, holiday.Date{}, fmt.Errorf("结束日期 %s 早于开始日期 %s", end, start)
+	}
+	if end.Before(today) {
+		return holiday.Date{}, holiday.Date{}, fmt.Errorf("请假已经结束，无需登记")
+	}
+	return start, end, nil
+}
+
+// parseLeaveDate 解析 MM-DD 或 YYYY-MM-DD，省略年份时取不早于 notBefore 的最近有效日期
+func parseLeaveDate(s string, notBefore holiday.Date) (holiday.Date, error) {
+	s = strings.ReplaceAll(s, "/", "-")
+	if strings.Count(s, "-") == 2 {
+		d, err := holiday.ParseDate(s)
+		if err != nil {
+			return holiday.Date{}, fmt.Errorf("%v\n%s", err, leaveUsage)
+		}
+		return d, nil
+	}
+
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return holiday.Date{}, fmt.Errorf("日期 %q 无效\n%s", s, leaveUsage)
+	}
+	month, err1 := strconv.Atoi(parts[0])
+	day, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return holiday.Date{}, fmt.Errorf("日期 %q 无效\n%s", s, leaveUsage)
+	}
+
+	// 02-29 在非闰年无效，顺延到之后的闰年；四年内总有一个闰年
+	for year := notBefore.Year; year <= notBefore.Year+4; year++ {
+		d, err := holiday.ParseDate(fmt.Sprintf("%04d-%02d-%02d", year, month, day))
+		if err != nil {
+			continue
+		}
+		if !d.Before(notBefore) {
+			return d, nil
+		}
+	}
+	return holiday.Date{}, fmt.Errorf("日期 %q 无效\n%s", s, leaveUsage)
+}
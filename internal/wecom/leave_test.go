@@ -0,0 +1,76 @@
+package wecom
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wechatrobot/internal/holiday"
+)
+
+func TestParseLeaveDates(t *testing.T) {
+	today := holiday.Date{Year: 2026, Month: 10, Day: 19}
+	tests := []struct {
+		args       string
+		start, end string
+		wantErr    bool
+	}{
+		{"10-20 10-25", "2026-10-20", "2026-10-25", false},
+		{"10-20", "2026-10-20", "2026-10-20", false},
+		{"12-30 01-03", "2026-12-30", "2027-01-03", false},
+		{"01-05", "2027-01-05", "2027-01-05", false},
+		{"2026-10-19 2026-10-21", "2026-10-19", "2026-10-21", false},
+		{"10/20 10/21", "2026-10-20", "2026-10-21", false},
+		{"02-29", "2028-02-29", "2028-02-29", false},
+		{"02-30", "", "", true},
+		{"10-25 2026-10-20", "", "", true},
+		{"2026-10-01 2026-10-02", "", "", true},
+		{"13-01", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		start, end, err := parseLeaveDates(strings.Fields(tt.args), today)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLeaveDates(%q) should fail, got %s..%s", tt.args, start, end)
+			}
+			continue
+		}
+		if err != nil || start.String() != tt.start || end.String() != tt.end {
+			t.Errorf("parseLeaveDates(%q) = %s..%s, %v; want %s..%s", tt.args, start, end, err, tt.start, tt.end)
+		}
+	}
+}
+
+func TestLeaveReply(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "leaves.jsonl")
+	if err := holiday.InitLeaves(holiday.LeaveConfig{File: file}); err != nil {
+		t.Fatal(err)
+	}
+	defer holiday.InitLeaves(holiday.LeaveConfig{})
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, holiday.Location())
+	if reply := leaveReply(context.Background(), "/请假 10-20 10-25", "alice", now); !strings.Contains(reply, "10月20日至10月25日，共6天") {
+		t.Errorf("reply = %q", reply)
+	}
+	if got := holiday.OnLeave(now.AddDate(0, 0, 2)); len(got) != 1 || got[0].User != "alice" {
+		t.Fatalf("OnLeave = %+v", got)
+	}
+
+	// 重新加载后请假仍然有效
+	if err := holiday.InitLeaves(holiday.LeaveConfig{File: file}); err != nil {
+		t.Fatal(err)
+	}
+	if len(holiday.OnLeave(now.AddDate(0, 0, 2))) != 1 {
+		t.Fatal("leave should be persisted")
+	}
+
+	if reply := leaveReply(context.Background(), "/销假", "alice", now); !strings.Contains(reply, "已撤销 1 条请假") {
+		t.Errorf("cancel reply = %q", reply)
+	}
+	if len(holiday.OnLeave(now.AddDate(0, 0, 2))) != 0 {
+		t.Error("leave should be cancelled")
+	}
+}
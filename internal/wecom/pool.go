@@ -0,0 +1,75 @@
+package wecom
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// chatTask 一条待处理的群聊提问
+type chatTask struct {
+	requestID string // 回调消息 ID，用于日志
+	question  string
+	userID    string
+}
+
+// workerPool 固定数量的 worker 处理有界队列中的提问
+type workerPool struct {
+	tasks  chan chatTask
+	wg     sync.WaitGroup
+	active int64 // 正在处理的任务数
+}
+
+// newWorkerPool 启动 workers 个 worker，队列最多缓存 queueSize 条提问
+func newWorkerPool(workers, queueSize int, handle func(chatTask)) *workerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &workerPool{tasks: make(chan chatTask, queueSize)}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				atomic.AddInt64(&p.active, 1)
+				handle(task)
+				atomic.AddInt64(&p.active, -1)
+			}
+		}()
+	}
+	return p
+}
+
+// Submit 提交提问，队列已满时返回 false，不会阻塞
+func (p *workerPool) Submit(task chatTask) bool {
+	select {
+	case p.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// QueueLength 当前排队中的提问数
+func (p *workerPool) QueueLength() int {
+	return len(p.tasks)
+}
+
+// QueueCapacity 队列容量
+func (p *workerPool) QueueCapacity() int {
+	return cap(p.tasks)
+}
+
+// Active 正在处理的提问数
+func (p *workerPool) Active() int {
+	return int(atomic.LoadInt64(&p.active))
+}
+
+// Close 不再接收新的提问，等待队列中和正在处理的提问完成
+func (p *workerPool) Close() {
+	close(p.tasks)
+	p.wg.Wait()
+}